@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/config"
+	"github.com/rmrfslashbin/manuals-cli/internal/credentials"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	authWithKey  bool
+	authNoVerify bool
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage API key storage",
+	Long: `Store, inspect and remove the API key used for the current API URL.
+
+Keys are stored per API URL (so each profile can have its own) in the
+Secret Service keyring when available, or otherwise in an encrypted file
+protected by MANUALS_KEYRING_PASSPHRASE. Set credential_store to
+secret-service or file to choose explicitly.
+
+The key is taken from, in order: --api-key, MANUALS_API_KEY, api_key in
+the config file, the output of api_key_command, and the stored key.`,
+	Annotations: map[string]string{annotationNoClient: "true"},
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store an API key",
+	Long: `Store an API key for the current API URL.
+
+The key is read from the terminal without echo, or from stdin with
+--with-key. It is checked against the server before being stored unless
+--no-verify is given.`,
+	Example: `  manuals auth login
+  manuals --profile staging auth login
+  pass show manuals | manuals auth login --with-key`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := credentialStore()
+		if err != nil {
+			return err
+		}
+
+		key, err := readAPIKey(authWithKey)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			return errors.New("no API key given")
+		}
+
+		if !authNoVerify {
+			if err := verifyAPIKey(cmd.Context(), key); err != nil {
+				return err
+			}
+		}
+
+		if err := store.Set(cfg.APIBaseURL, key); err != nil {
+			return fmt.Errorf("failed to store API key: %w", err)
+		}
+		out.Text("Stored API key for %s in %s\n", cfg.APIBaseURL, store.Name())
+		return nil
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored API key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := credentialStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(cfg.APIBaseURL); err != nil {
+			return fmt.Errorf("failed to remove API key: %w", err)
+		}
+		out.Text("Removed stored API key for %s from %s\n", cfg.APIBaseURL, store.Name())
+		return nil
+	},
+}
+
+// authStatus is the result of `auth status`.
+type authStatus struct {
+	APIURL        string `json:"api_url"`
+	Profile       string `json:"profile,omitempty"`
+	KeySource     string `json:"key_source"`
+	Key           string `json:"key,omitempty"`
+	Authenticated bool   `json:"authenticated"`
+	Error         string `json:"error,omitempty"`
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show where the API key comes from and whether it works",
+	Example: `  manuals auth status`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status := authStatus{APIURL: cfg.APIBaseURL, Profile: cfg.Profile}
+
+		var verifyErr error
+		source, err := resolveAPIKey(cmd.Context())
+		if err == nil && source == "" && cfg.APIKey != "" {
+			source, err = configKeySource()
+		}
+		switch {
+		case err != nil:
+			status.KeySource = "error"
+			status.Error = err.Error()
+		case cfg.APIKey == "":
+			status.KeySource = "none"
+			status.Error = "no API key configured"
+		default:
+			status.KeySource = source
+			status.Key = config.Redact(cfg.APIKey)
+			if verifyErr = verifyAPIKey(cmd.Context(), cfg.APIKey); verifyErr != nil {
+				status.Error = verifyErr.Error()
+			} else {
+				status.Authenticated = true
+			}
+		}
+
+		if out.IsStructured() {
+			if err := out.Value(status); err != nil {
+				return err
+			}
+		} else {
+			out.Text("API URL:    %s\n", status.APIURL)
+			if status.Profile != "" {
+				out.Text("Profile:    %s\n", status.Profile)
+			}
+			out.Text("Key source: %s\n", status.KeySource)
+			if status.Key != "" {
+				out.Text("Key:        %s\n", status.Key)
+			}
+			if status.Authenticated {
+				out.Text("Status:     authenticated\n")
+			} else {
+				out.Text("Status:     not authenticated (%s)\n", status.Error)
+			}
+		}
+
+		if !status.Authenticated {
+			if err != nil {
+				return err
+			}
+			if verifyErr != nil {
+				return verifyErr
+			}
+			return &client.APIError{StatusCode: 401, Message: status.Error}
+		}
+		return nil
+	},
+}
+
+// resolveAPIKey fills in cfg.APIKey from api_key_command or the credential
+// store when it is not set by a flag, the environment or the config file.
+// It returns the source it used, or "" if the key was already set or none
+// was found.
+func resolveAPIKey(ctx context.Context) (string, error) {
+	if cfg.APIKey != "" {
+		return "", nil
+	}
+
+	if cfg.APIKeyCommand != "" {
+		key, err := credentials.RunCommand(ctx, cfg.APIKeyCommand)
+		if err != nil {
+			return "", err
+		}
+		cfg.APIKey = key
+		return "api_key_command", nil
+	}
+
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+	key, err := store.Get(cfg.APIBaseURL)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read stored API key: %w", err)
+	}
+	cfg.APIKey = key
+	return store.Name(), nil
+}
+
+// configKeySource reports where an API key that was set before
+// resolveAPIKey came from: the --api-key flag, or the source of api_key in
+// the config settings.
+func configKeySource() (string, error) {
+	if apiKey != "" {
+		return "flag --api-key", nil
+	}
+	settings, err := config.Settings(loadOptions())
+	if err != nil {
+		return "", err
+	}
+	for _, s := range settings {
+		if s.Key == "api_key" {
+			return s.Source, nil
+		}
+	}
+	return "config", nil
+}
+
+// credentialStore opens the configured credential store.
+func credentialStore() (credentials.Store, error) {
+	path, err := config.CredentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.Open(cfg.CredentialStore, path)
+}
+
+// verifyAPIKey checks key by making a minimal authenticated request.
+func verifyAPIKey(ctx context.Context, key string) error {
+	c := client.New(cfg.APIBaseURL, key, client.WithTimeout(cfg.Timeout))
+	if _, err := c.ListDevicesContext(ctx, 1, 0, "", ""); err != nil {
+		return fmt.Errorf("API key check failed: %w", err)
+	}
+	return nil
+}
+
+// readAPIKey reads a key from stdin, prompting without echo when stdin is
+// a terminal.
+func readAPIKey(fromStdin bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) && !fromStdin {
+		fmt.Fprintf(os.Stderr, "API key for %s: ", cfg.APIBaseURL)
+		key, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key: %w", err)
+		}
+		return strings.TrimSpace(string(key)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read API key: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authStatusCmd)
+
+	authLoginCmd.Flags().BoolVar(&authWithKey, "with-key", false, "read the API key from stdin")
+	authLoginCmd.Flags().BoolVar(&authNoVerify, "no-verify", false, "store the key without checking it against the server")
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/bom"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bomPartCol     string
+	bomMfrCol      string
+	bomDescCol     string
+	bomNoHeader    bool
+	bomDelimiter   string
+	bomLimit       int
+	bomMinScore    float64
+	bomMargin      float64
+	bomDir         string
+	bomDryRun      bool
+	bomConcurrency int
+	bomForce       bool
+)
+
+var bomCmd = &cobra.Command{
+	Use:   "bom",
+	Short: "Fetch documentation for a bill of materials",
+	Long:  `Match the parts of a bill of materials to devices and download their documents.`,
+}
+
+var bomImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Match BOM parts to devices and download their documents",
+	Long: `Read a bill of materials in CSV form (or TSV, for .tsv files or with
+--delimiter) and search for the device of each part by its part number
+and manufacturer, or its description if it has no part number.
+
+Columns are found from the header line by common names (part number,
+MPN, manufacturer, description, ...); --part-col, --manufacturer-col and
+--description-col name them, by header or 1-based number. Without a
+header line (--no-header) they must be given by number.
+
+A part matches the device whose name contains its part number, or else
+the best result scoring at least --min-score and more than --margin ahead
+of the next. Parts with several such devices are "ambiguous" and parts
+with none "unmatched"; both are listed for review with their candidates.
+
+The documents of each matched device are downloaded into a folder per
+part under --dir, and an index.md report linking them is written there.
+Files already present with the right checksum, or of documents without a
+checksum, are skipped, so the import can be re-run as the BOM changes.
+--dry-run only matches the parts.`,
+	Example: `  manuals bom import bom.csv
+  manuals bom import bom.csv --dry-run
+  manuals bom import bom.tsv --dir ./datasheets --min-score 0.6
+  manuals bom import parts.csv --part-col MPN --manufacturer-col Vendor
+  manuals bom import - --no-header --part-col 2 --manufacturer-col 3 < parts.csv`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		source := args[0]
+
+		comma, err := bomComma(source)
+		if err != nil {
+			return &usageError{err: err, cmdPath: cmd.CommandPath()}
+		}
+		var r io.Reader = os.Stdin
+		if source != "-" {
+			f, err := os.Open(source)
+			if err != nil {
+				return fmt.Errorf("failed to open BOM: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		cols := bom.Columns{Part: bomPartCol, Manufacturer: bomMfrCol, Description: bomDescCol}
+		parts, err := bom.Read(r, cols, comma, !bomNoHeader)
+		if err != nil {
+			return fmt.Errorf("failed to read BOM %s: %w", source, err)
+		}
+
+		opts := bom.MatchOptions{MinScore: bomMinScore, Margin: bomMargin}
+		results := make([]bom.Result, len(parts))
+		stats := downloadStats{dir: bomDir}
+		for i, p := range parts {
+			res := bom.Result{Part: p}
+			resp, err := apiClient.SearchContext(ctx, p.Query(), bomLimit)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				res.Status = bom.Unmatched
+				res.Error = fmt.Sprintf("search failed: %v", err)
+				results[i] = res
+				continue
+			}
+
+			status, device, candidates := bom.Match(p, resp.Results, opts)
+			res.Status = status
+			for _, c := range candidates {
+				res.Candidates = append(res.Candidates, fmt.Sprintf("%s  %s (%.2f)", c.DeviceID, c.Name, c.Score))
+			}
+			if device != nil {
+				res.DeviceID, res.DeviceName, res.Score = device.DeviceID, device.Name, device.Score
+			}
+			if device != nil && !bomDryRun {
+				if err := bomDownload(cmd, &res, &stats); err != nil {
+					return err
+				}
+			}
+			results[i] = res
+		}
+
+		review := 0
+		for _, res := range results {
+			if res.NeedsReview() {
+				review++
+			}
+		}
+
+		report := filepath.Join(bomDir, bom.ReportName)
+		if !bomDryRun {
+			if err := os.MkdirAll(bomDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := writeBOMReport(report, source, results); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+
+		if out.IsStructured() {
+			page := struct {
+				Source  string       `json:"source"`
+				Dir     string       `json:"dir,omitempty"`
+				Review  int          `json:"review"`
+				Results []bom.Result `json:"results"`
+			}{Source: source, Review: review, Results: results}
+			if !bomDryRun {
+				page.Dir = bomDir
+			}
+			if err := out.List(page, results); err != nil {
+				return err
+			}
+		} else {
+			rows := make([][]string, len(results))
+			for i, res := range results {
+				score := ""
+				if res.Status == bom.Matched {
+					score = out.Score(res.Score)
+				}
+				status := string(res.Status)
+				if res.Error != "" {
+					status += " (error)"
+				}
+				rows[i] = []string{
+					strconv.Itoa(res.Line), res.Number, res.Manufacturer, status,
+					res.DeviceName, score, strconv.Itoa(len(res.Documents)),
+				}
+			}
+			out.Table([]string{"LINE", "PART", "MANUFACTURER", "STATUS", "DEVICE", "SCORE", "DOCS"}, rows)
+			if out.IsHuman() {
+				if !bomDryRun {
+					out.Text("\n%s\nWrote %s\n", stats, report)
+				}
+				if review > 0 {
+					out.Text("%d of %d parts need review\n", review, len(results))
+				}
+			}
+		}
+
+		return stats.err(ctx)
+	},
+}
+
+// bomDownload downloads the documents of the device matched by res into
+// the part's folder, recording the files and any failure in res.
+func bomDownload(cmd *cobra.Command, res *bom.Result, stats *downloadStats) error {
+	ctx := cmd.Context()
+	var docs []*client.Document
+	for d, err := range apiClient.AllDocuments(ctx, 0, res.DeviceID) {
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			res.Error = fmt.Sprintf("failed to list documents: %v", err)
+			return nil
+		}
+		docs = append(docs, &d)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	folder := res.Folder()
+	opts := downloadOptions{
+		concurrency:  bomConcurrency,
+		force:        bomForce,
+		keepExisting: true,
+	}
+	all, s, err := downloadAll(ctx, docs, filepath.Join(bomDir, folder), opts)
+	if err != nil {
+		return err
+	}
+	stats.downloaded += s.downloaded
+	stats.skipped += s.skipped
+	stats.failed += s.failed
+	stats.count += s.count
+	stats.bytes += s.bytes
+	stats.mismatched += s.mismatched
+	if s.mismatch != nil {
+		stats.mismatch = s.mismatch
+	}
+
+	var failed []string
+	for _, d := range all {
+		if d.err != nil {
+			failed = append(failed, d.Filename)
+			continue
+		}
+		res.Documents = append(res.Documents, filepath.Join(folder, filepath.Base(d.Path)))
+	}
+	if len(failed) > 0 {
+		res.Error = "failed to download " + strings.Join(failed, ", ")
+	}
+	return nil
+}
+
+// writeBOMReport writes the index report to path.
+func writeBOMReport(path, source string, results []bom.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bom.WriteReport(f, source, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// bomComma returns the field separator for the BOM: --delimiter, or a tab
+// for .tsv files and a comma otherwise.
+func bomComma(source string) (rune, error) {
+	switch d := bomDelimiter; {
+	case d == `\t` || d == "tab":
+		return '\t', nil
+	case d != "":
+		r, size := utf8.DecodeRuneInString(d)
+		if size != len(d) || r == '"' || r == '\n' || r == '\r' {
+			return 0, errors.New("--delimiter must be a single character other than a quote or newline")
+		}
+		return r, nil
+	case strings.EqualFold(filepath.Ext(source), ".tsv"):
+		return '\t', nil
+	default:
+		return ',', nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(bomCmd)
+	bomCmd.AddCommand(bomImportCmd)
+
+	bomImportCmd.Flags().StringVar(&bomPartCol, "part-col", "", "part number column, by header name or 1-based number")
+	bomImportCmd.Flags().StringVar(&bomMfrCol, "manufacturer-col", "", "manufacturer column, by header name or 1-based number")
+	bomImportCmd.Flags().StringVar(&bomDescCol, "description-col", "", "description column, by header name or 1-based number")
+	bomImportCmd.Flags().BoolVar(&bomNoHeader, "no-header", false, "the BOM has no header line")
+	bomImportCmd.Flags().StringVar(&bomDelimiter, "delimiter", "", `field separator, such as ";" or "tab" (default: tab for .tsv files, else comma)`)
+	bomImportCmd.Flags().IntVarP(&bomLimit, "limit", "l", 5, "search results to consider per part")
+	bomImportCmd.Flags().Float64Var(&bomMinScore, "min-score", 0.5, "minimum score for a device to match")
+	bomImportCmd.Flags().Float64Var(&bomMargin, "margin", 0.05, "how far the best device must lead the next to match")
+	bomImportCmd.Flags().StringVar(&bomDir, "dir", "bom-docs", "directory to download documents and write the report into")
+	bomImportCmd.Flags().BoolVar(&bomDryRun, "dry-run", false, "match parts without downloading anything")
+	bomImportCmd.Flags().IntVarP(&bomConcurrency, "concurrency", "j", defaultConcurrency, "number of parallel downloads")
+	bomImportCmd.Flags().BoolVarP(&bomForce, "force", "f", false, "overwrite existing files")
+}
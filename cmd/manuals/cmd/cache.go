@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/cache"
+	"github.com/rmrfslashbin/manuals-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local response cache",
+	Long: `Manage the on-disk cache of API responses.
+
+Responses are cached under the XDG cache directory (or cache_dir) and
+served without contacting the server for cache_ttl (default 5m). Stale
+entries are revalidated with ETags when the server supports them. Use
+--offline on any command to serve responses only from the cache.`,
+	Annotations: map[string]string{annotationNoClient: "true"},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache statistics",
+	Example: `  manuals cache stats
+  manuals cache stats -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+
+		if out.IsStructured() {
+			return out.Value(stats)
+		}
+
+		out.Text("Cache: %s\n", stats.Dir)
+		out.Text("  TTL:       %s\n", stats.TTL)
+		out.Text("  Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+		out.Text("  Size:      %s\n", output.FormatSize(stats.Bytes))
+
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		removed, err := c.Clear()
+		if err != nil {
+			return err
+		}
+		out.Text("Removed %d cached responses.\n", removed)
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		removed, err := c.Prune()
+		if err != nil {
+			return err
+		}
+		out.Text("Removed %d expired responses.\n", removed)
+		return nil
+	},
+}
+
+// openCache returns the configured response cache.
+func openCache() (*cache.Cache, error) {
+	if cfg.CacheDir == "" {
+		return nil, fmt.Errorf("no cache directory: set cache_dir or MANUALS_CACHE_DIR")
+	}
+	return cache.New(cfg.CacheDir, cfg.CacheTTL), nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+}
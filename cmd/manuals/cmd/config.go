@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage CLI configuration",
+	Long: `Manage the CLI configuration file and profiles.
+
+The config file is taken from --config, then MANUALS_CONFIG, then the first
+that exists of ~/.manuals.yaml, ./.manuals.yaml and
+~/.config/manuals/config.yaml (each also as .yml or .json). JSON files
+are read but not edited by 'config set'.
+
+A ./.manuals.yaml in the working directory may come with a checked-out
+project, so it cannot set the keys that run commands (api_key_command and
+pager); they are ignored with a warning.
+
+Profiles are named sets of settings (api_url, api_key, output_format and
+any other key) under "profiles" in the config file. The active profile is
+chosen by --profile, then MANUALS_PROFILE, then current_profile.`,
+	Annotations: map[string]string{
+		annotationNoClient:       "true",
+		annotationLenientProfile: "true",
+	},
+}
+
+var configListProfilesCmd = &cobra.Command{
+	Use:   "list-profiles",
+	Short: "List configured profiles",
+	Example: `  manuals config list-profiles
+  manuals config list-profiles -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, _, err := config.Profiles(cfgFile)
+		if err != nil {
+			return err
+		}
+
+		// Mark the profile in effect, which flags or env may override.
+		for i := range profiles {
+			profiles[i].Current = profiles[i].Name == cfg.Profile
+		}
+
+		if out.IsStructured() {
+			return out.List(profiles, profiles)
+		}
+
+		if len(profiles) == 0 {
+			out.Println("No profiles configured.")
+			return nil
+		}
+
+		headers := []string{"CURRENT", "NAME", "API URL"}
+		rows := make([][]string, len(profiles))
+		for i, p := range profiles {
+			current := ""
+			if p.Current {
+				current = "*"
+			}
+			rows[i] = []string{current, p.Name, p.APIURL}
+		}
+		out.Table(headers, rows)
+
+		return nil
+	},
+}
+
+var configUseProfileCmd = &cobra.Command{
+	Use:     "use-profile <name>",
+	Short:   "Set the default profile",
+	Long:    `Set current_profile in the config file, making <name> the default profile.`,
+	Example: `  manuals config use-profile staging`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.UseProfile(cfgFile, args[0])
+		if err != nil {
+			return err
+		}
+		out.Text("Switched to profile %q (%s)\n", args[0], path)
+		return nil
+	},
+}
+
+var (
+	configInitForce     bool
+	configGetShowSecret bool
+)
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective configuration",
+	Long: `Show the effective configuration after applying the config file, the
+active profile, environment variables and flags. The API key is redacted.`,
+	Example: `  manuals config view
+  manuals --profile staging config view -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := effectiveSettings(cmd)
+		if err != nil {
+			return err
+		}
+
+		if out.IsStructured() {
+			values := make(map[string]string, len(settings))
+			for _, s := range settings {
+				values[s.Key] = s.Redacted()
+			}
+			return out.Value(values)
+		}
+
+		if cfg.File != "" {
+			out.Text("# %s\n", cfg.File)
+		}
+		if cfg.Profile != "" {
+			out.Text("# profile: %s\n", cfg.Profile)
+		}
+		for _, s := range settings {
+			out.Text("%s: %s\n", s.Key, s.Redacted())
+		}
+
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a config key",
+	Long: `Print the effective value of a config key. Secret values such as
+api_key are masked, as in 'config view', unless --show-secret is given.`,
+	Example: `  manuals config get api_url
+  manuals --profile staging config get timeout
+  manuals config get api_key --show-secret`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := effectiveSettings(cmd)
+		if err != nil {
+			return err
+		}
+		for _, s := range settings {
+			if s.Key == args[0] {
+				if !configGetShowSecret {
+					s.Value = s.Redacted()
+				}
+				if out.IsStructured() {
+					return out.Value(s)
+				}
+				out.Println(s.Value)
+				return nil
+			}
+		}
+		return &usageError{err: fmt.Errorf("unknown config key %q (see 'manuals config view')", args[0]), cmdPath: cmd.CommandPath()}
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config key in the config file",
+	Long: `Set a config key in the config file, creating the file if needed.
+
+If a profile is active (--profile, MANUALS_PROFILE or current_profile),
+the key is set in that profile instead of at the top level.
+Values are checked before writing: numbers, durations, and the choices of
+keys such as color, credential_store and output_format. Comments and the
+layout of the file are preserved.`,
+	Example: `  manuals config set api_url http://manuals.local:8080
+  manuals config set timeout 1m
+  manuals --profile staging config set api_url https://manuals-staging.example.com`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := cfg.File
+		if path == "" {
+			var err error
+			if path, err = config.DefaultFile(); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+				return err
+			}
+		}
+
+		if err := config.Set(path, cfg.Profile, args[0], args[1]); err != nil {
+			return err
+		}
+
+		target := args[0]
+		if cfg.Profile != "" {
+			target = fmt.Sprintf("%s (profile %s)", args[0], cfg.Profile)
+		}
+		out.Text("Set %s in %s\n", target, path)
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show where each config value comes from",
+	Long: `Show the config file in use and, for each key, whether its value comes
+from a flag, an environment variable, the active profile, the config file
+or the built-in default.`,
+	Example: `  manuals config path
+  MANUALS_TIMEOUT=1m manuals config path`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := effectiveSettings(cmd)
+		if err != nil {
+			return err
+		}
+
+		if out.IsStructured() {
+			for i := range settings {
+				settings[i].Value = settings[i].Redacted()
+			}
+			return out.Value(map[string]interface{}{
+				"file":     cfg.File,
+				"profile":  cfg.Profile,
+				"settings": settings,
+			})
+		}
+
+		file := cfg.File
+		if file == "" {
+			file = "(none)"
+		}
+		out.Text("Config file: %s\n", file)
+		if cfg.Profile != "" {
+			out.Text("Profile:     %s\n", cfg.Profile)
+		}
+		out.Println()
+
+		headers := []string{"KEY", "VALUE", "SOURCE"}
+		rows := make([][]string, len(settings))
+		for i, s := range settings {
+			rows[i] = []string{s.Key, s.Redacted(), s.Source}
+		}
+		out.Table(headers, rows)
+
+		return nil
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a starter config file",
+	Long: `Write a commented starter config file to ~/.config/manuals/config.yaml
+(or $XDG_CONFIG_HOME/manuals/config.yaml, or the --config path).
+
+Values given with --api-url and --api-key are filled in.`,
+	Example: `  manuals config init
+  manuals config init --api-url http://manuals.local:8080 --api-key KEY`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := cfgFile
+		if path == "" {
+			var err error
+			if path, err = config.DefaultFile(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := os.Stat(path); err == nil && !configInitForce {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, config.Starter(apiURL, apiKey), 0o600); err != nil {
+			return err
+		}
+
+		out.Text("Wrote %s\n", path)
+		return nil
+	},
+}
+
+// settingFlags maps config keys to the global flags that override them.
+var settingFlags = map[string]string{
+	"api_url":       "api-url",
+	"api_key":       "api-key",
+	"color":         "color",
+	"output_format": "output",
+	"timeout":       "timeout",
+	"retries":       "retries",
+}
+
+// effectiveSettings resolves the config settings, including flag overrides.
+func effectiveSettings(cmd *cobra.Command) ([]config.Setting, error) {
+	settings, err := config.Settings(loadOptions())
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range settings {
+		name, ok := settingFlags[s.Key]
+		if !ok {
+			continue
+		}
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			settings[i].Value = f.Value.String()
+			settings[i].Source = "flag --" + name
+		}
+	}
+	return settings, nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configListProfilesCmd)
+	configCmd.AddCommand(configUseProfileCmd)
+
+	configGetCmd.Flags().BoolVar(&configGetShowSecret, "show-secret", false, "print secret values unmasked")
+	configInitCmd.Flags().BoolVarP(&configInitForce, "force", "f", false, "overwrite an existing config file")
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
 	"github.com/rmrfslashbin/manuals-cli/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -12,8 +14,20 @@ var (
 	devicesOffset int
 	devicesDomain string
 	devicesType   string
+	devicesAll    bool
+	devicesRaw    bool
 )
 
+// deviceColumns are the table columns of device listings.
+var deviceColumns = []output.Column{
+	{Name: "ID", Field: "id", Format: output.ShortIDCell},
+	{Name: "NAME", Field: "name"},
+	{Name: "DOMAIN", Field: "domain"},
+	{Name: "TYPE", Field: "type"},
+	{Name: "PATH", Field: "path", Wide: true},
+	{Name: "INDEXED", Field: "indexed_at", Wide: true},
+}
+
 var devicesCmd = &cobra.Command{
 	Use:   "devices",
 	Short: "List and manage devices",
@@ -25,41 +39,53 @@ var devicesListCmd = &cobra.Command{
 	Short: "List all devices",
 	Long: `List devices in the Manuals database with optional filtering.
 
-Filter by domain (hardware, software) or type (dev-boards, sensors, etc.).`,
+Filter by domain (hardware, software) or type (dev-boards, sensors, etc.).
+
+Use --all to page through every matching device; --limit then sets the
+page size. Results are printed as each page arrives.
+
+` + tableFlagsHelp + `
+
+Columns: ID, NAME, DOMAIN, TYPE, PATH and INDEXED; -o wide shows all of
+them. Other fields of the JSON output, such as metadata.vendor, can also be
+used as columns.`,
 	Example: `  manuals devices list
   manuals devices list --domain hardware
   manuals devices list --type dev-boards --limit 10
-  manuals devices list -o json`,
+  manuals devices list --all -o json
+  manuals devices list -o wide --sort-by indexed --reverse
+  manuals devices list --columns id,name,metadata.vendor --no-headers`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		result, err := apiClient.ListDevices(devicesLimit, devicesOffset, devicesDomain, devicesType)
+		out.SetTableOptions(tableOptions())
+
+		if devicesAll {
+			return listAllDevices(cmd)
+		}
+
+		result, err := apiClient.ListDevicesContext(cmd.Context(), devicesLimit, devicesOffset, devicesDomain, devicesType)
 		if err != nil {
 			return fmt.Errorf("failed to list devices: %w", err)
 		}
 
-		if out.IsJSON() {
-			return out.JSON(result)
+		if out.IsStructured() {
+			return out.List(result, result.Data)
 		}
 
-		if len(result.Data) == 0 {
-			out.Println("No devices found.")
-			return nil
+		if out.IsHuman() {
+			if len(result.Data) == 0 {
+				out.Println("No devices found.")
+				return nil
+			}
+			if !tableNoHeaders {
+				out.Text("Showing %d of %d devices:\n\n", len(result.Data), result.Total)
+			}
 		}
 
-		out.Text("Showing %d of %d devices:\n\n", len(result.Data), result.Total)
-
-		headers := []string{"ID", "NAME", "DOMAIN", "TYPE"}
-		rows := make([][]string, len(result.Data))
-		for i, d := range result.Data {
-			rows[i] = []string{
-				d.ID[:8],
-				output.Truncate(d.Name, 45),
-				d.Domain,
-				d.Type,
-			}
+		if err := out.Records(deviceColumns, result.Data); err != nil {
+			return err
 		}
-		out.Table(headers, rows)
 
-		if result.Total > len(result.Data) {
+		if out.IsHuman() && !tableNoHeaders && result.Total > len(result.Data) {
 			out.Text("\nUse --offset %d to see more results.\n", result.Offset+len(result.Data))
 		}
 
@@ -67,23 +93,79 @@ Filter by domain (hardware, software) or type (dev-boards, sensors, etc.).`,
 	},
 }
 
+// listAllDevices streams every matching device across all pages.
+func listAllDevices(cmd *cobra.Command) error {
+	pageSize := devicesLimit
+	if pageSize <= 0 {
+		pageSize = client.DefaultPageSize
+	}
+
+	stream := out.Stream(deviceColumns)
+	for d, err := range apiClient.AllDevices(cmd.Context(), pageSize, devicesDomain, devicesType) {
+		if err != nil {
+			// Terminate the listing so what was written stays well-formed.
+			_ = stream.Close()
+			return fmt.Errorf("failed to list devices: %w", err)
+		}
+
+		if err := stream.Add(d); err != nil {
+			return err
+		}
+		if stream.Count()%pageSize == 0 {
+			stream.Flush()
+		}
+	}
+	if err := stream.Close(); err != nil {
+		return err
+	}
+
+	if out.IsHuman() {
+		if stream.Count() == 0 {
+			out.Println("No devices found.")
+		} else if !tableNoHeaders {
+			out.Text("\n%d devices.\n", stream.Count())
+		}
+	}
+
+	return nil
+}
+
 var devicesGetCmd = &cobra.Command{
-	Use:   "get <id>",
+	Use:   "get <id|name|path>",
 	Short: "Get device details",
-	Long:  `Get detailed information about a specific device by ID.`,
+	Long: `Get detailed information about a specific device.
+
+The device may be given by ID, by a unique ID prefix such as the short IDs
+shown by 'devices list', or by its name or path.
+
+On a terminal the device's Markdown content is rendered with styled
+headings, wrapped paragraphs, aligned tables and highlighted code; --raw
+prints it as is. -o markdown writes a Markdown document with the device
+fields as YAML front matter, followed by the content.`,
 	Example: `  manuals devices get abc12345
-  manuals devices get abc12345 -o json`,
+  manuals devices get abc12345 -o json
+  manuals devices get abc12345 -o markdown > esp32.md
+  manuals devices get "ESP32-S3-DevKitC-1"
+  manuals devices get hardware/dev-boards/esp32-s3-devkitc-1`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id := args[0]
-
-		device, err := apiClient.GetDevice(id)
+		device, err := apiClient.ResolveDevice(cmd.Context(), args[0])
 		if err != nil {
 			return fmt.Errorf("failed to get device: %w", err)
 		}
 
-		if out.IsJSON() {
-			return out.JSON(device)
+		if out.IsStructured() {
+			return out.Value(device)
+		}
+		if out.Format() == output.FormatMarkdown {
+			fields := *device
+			fields.Content = ""
+			return out.MarkdownDocument(fields, device.Content)
+		}
+		if out.IsDelimited() {
+			out.Table([]string{"ID", "NAME", "DOMAIN", "TYPE", "PATH", "INDEXED"},
+				[][]string{{device.ID, device.Name, device.Domain, device.Type, device.Path, device.IndexedAt}})
+			return nil
 		}
 
 		out.Text("Device: %s\n", device.Name)
@@ -93,14 +175,32 @@ var devicesGetCmd = &cobra.Command{
 		out.Text("  Path:      %s\n", device.Path)
 		out.Text("  Indexed:   %s\n", device.IndexedAt)
 
-		if device.Content != "" {
+		switch {
+		case device.Content == "":
+		case devicesRaw || !out.IsTerminal():
 			out.Text("\n--- Content ---\n%s\n", device.Content)
+		default:
+			out.Println()
+			return out.Markdown(device.Content)
 		}
 
 		return nil
 	},
 }
 
+// resolveDeviceID returns the full ID of the device identified by ref, an
+// ID, unique ID prefix, name or path. An empty ref is returned unchanged.
+func resolveDeviceID(ctx context.Context, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	device, err := apiClient.ResolveDevice(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get device: %w", err)
+	}
+	return device.ID, nil
+}
+
 func init() {
 	rootCmd.AddCommand(devicesCmd)
 	devicesCmd.AddCommand(devicesListCmd)
@@ -110,4 +210,9 @@ func init() {
 	devicesListCmd.Flags().IntVar(&devicesOffset, "offset", 0, "offset for pagination")
 	devicesListCmd.Flags().StringVarP(&devicesDomain, "domain", "d", "", "filter by domain (hardware, software)")
 	devicesListCmd.Flags().StringVarP(&devicesType, "type", "t", "", "filter by type")
+	devicesListCmd.Flags().BoolVar(&devicesAll, "all", false, "fetch all pages (--limit sets the page size)")
+	devicesListCmd.MarkFlagsMutuallyExclusive("all", "offset")
+	addTableFlags(devicesListCmd)
+
+	devicesGetCmd.Flags().BoolVar(&devicesRaw, "raw", false, "print content as is instead of rendering Markdown")
 }
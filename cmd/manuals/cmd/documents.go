@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
 	"github.com/rmrfslashbin/manuals-cli/internal/output"
+	"github.com/rmrfslashbin/manuals-cli/internal/safename"
 	"github.com/spf13/cobra"
 )
 
@@ -15,8 +23,60 @@ var (
 	docsOffset   int
 	docsDeviceID string
 	docsOutput   string
+	docsAll      bool
+	docsResume   bool
+	docsForce    bool
+	docsNoVerify bool
+
+	docsDownloadDevice string
+	docsConcurrency    int
 )
 
+// defaultConcurrency is the default number of parallel downloads.
+const defaultConcurrency = 4
+
+// downloadOptions controls how documents are downloaded.
+type downloadOptions struct {
+	// concurrency is the number of parallel downloads of downloadAll.
+	concurrency int
+
+	// resume continues interrupted downloads from their .part files.
+	resume bool
+
+	// force overwrites existing files.
+	force bool
+
+	// noVerify skips checksum verification.
+	noVerify bool
+
+	// keepExisting takes existing files without a checksum to verify as
+	// up to date, instead of refusing to overwrite them.
+	keepExisting bool
+}
+
+// docsDownloadOptions returns the download options given by the flags of
+// 'documents download'.
+func docsDownloadOptions() downloadOptions {
+	return downloadOptions{
+		concurrency: docsConcurrency,
+		resume:      docsResume,
+		force:       docsForce,
+		noVerify:    docsNoVerify,
+	}
+}
+
+// documentColumns are the table columns of document listings.
+var documentColumns = []output.Column{
+	{Name: "ID", Field: "id", Format: output.ShortIDCell},
+	{Name: "FILENAME", Field: "filename"},
+	{Name: "TYPE", Field: "mime_type"},
+	{Name: "SIZE", Field: "size_bytes", Format: output.SizeCell},
+	{Name: "DEVICE", Field: "device_id", Format: output.ShortIDCell, Wide: true},
+	{Name: "PATH", Field: "path", Wide: true},
+	{Name: "CHECKSUM", Field: "checksum", Format: output.TruncateCell(19), Wide: true},
+	{Name: "INDEXED", Field: "indexed_at", Wide: true},
+}
+
 var documentsCmd = &cobra.Command{
 	Use:     "documents",
 	Aliases: []string{"docs"},
@@ -29,40 +89,60 @@ var documentsListCmd = &cobra.Command{
 	Short: "List all documents",
 	Long: `List documents in the Manuals database.
 
-Filter by device ID to see documents for a specific device.`,
+Filter by device to see documents for a specific device. The device may
+be given by ID, unique ID prefix, name or path.
+
+Use --all to page through every matching document; --limit then sets the
+page size. Results are printed as each page arrives.
+
+` + tableFlagsHelp + `
+
+Columns: ID, FILENAME, TYPE, SIZE, DEVICE, PATH, CHECKSUM and INDEXED;
+-o wide shows all of them. Other fields of the JSON output can also be used
+as columns.`,
 	Example: `  manuals documents list
   manuals docs list --device abc12345
-  manuals docs list --limit 20 -o json`,
+  manuals docs list --limit 20 -o json
+  manuals docs list --all
+  manuals docs list --all --sort-by size --reverse
+  manuals docs list --columns id,filename,checksum -o csv`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		result, err := apiClient.ListDocuments(docsLimit, docsOffset, docsDeviceID)
+		out.SetTableOptions(tableOptions())
+
+		deviceID, err := resolveDeviceID(cmd.Context(), docsDeviceID)
 		if err != nil {
-			return fmt.Errorf("failed to list documents: %w", err)
+			return err
 		}
+		docsDeviceID = deviceID
 
-		if out.IsJSON() {
-			return out.JSON(result)
+		if docsAll {
+			return listAllDocuments(cmd)
 		}
 
-		if len(result.Data) == 0 {
-			out.Println("No documents found.")
-			return nil
+		result, err := apiClient.ListDocumentsContext(cmd.Context(), docsLimit, docsOffset, docsDeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
 		}
 
-		out.Text("Showing %d of %d documents:\n\n", len(result.Data), result.Total)
+		if out.IsStructured() {
+			return out.List(result, result.Data)
+		}
 
-		headers := []string{"ID", "FILENAME", "TYPE", "SIZE"}
-		rows := make([][]string, len(result.Data))
-		for i, d := range result.Data {
-			rows[i] = []string{
-				d.ID[:8],
-				output.Truncate(d.Filename, 45),
-				d.MimeType,
-				output.FormatSize(d.SizeBytes),
+		if out.IsHuman() {
+			if len(result.Data) == 0 {
+				out.Println("No documents found.")
+				return nil
 			}
+			if !tableNoHeaders {
+				out.Text("Showing %d of %d documents:\n\n", len(result.Data), result.Total)
+			}
+		}
+
+		if err := out.Records(documentColumns, result.Data); err != nil {
+			return err
 		}
-		out.Table(headers, rows)
 
-		if result.Total > len(result.Data) {
+		if out.IsHuman() && !tableNoHeaders && result.Total > len(result.Data) {
 			out.Text("\nUse --offset %d to see more results.\n", result.Offset+len(result.Data))
 		}
 
@@ -70,23 +150,64 @@ Filter by device ID to see documents for a specific device.`,
 	},
 }
 
+// listAllDocuments streams every matching document across all pages.
+func listAllDocuments(cmd *cobra.Command) error {
+	pageSize := docsLimit
+	if pageSize <= 0 {
+		pageSize = client.DefaultPageSize
+	}
+
+	stream := out.Stream(documentColumns)
+	for d, err := range apiClient.AllDocuments(cmd.Context(), pageSize, docsDeviceID) {
+		if err != nil {
+			// Terminate the listing so what was written stays well-formed.
+			_ = stream.Close()
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		if err := stream.Add(d); err != nil {
+			return err
+		}
+		if stream.Count()%pageSize == 0 {
+			stream.Flush()
+		}
+	}
+	if err := stream.Close(); err != nil {
+		return err
+	}
+
+	if out.IsHuman() {
+		if stream.Count() == 0 {
+			out.Println("No documents found.")
+		} else if !tableNoHeaders {
+			out.Text("\n%d documents.\n", stream.Count())
+		}
+	}
+
+	return nil
+}
+
 var documentsGetCmd = &cobra.Command{
 	Use:   "get <id>",
 	Short: "Get document details",
-	Long:  `Get detailed information about a specific document by ID.`,
+	Long: `Get detailed information about a specific document by ID or by a
+unique ID prefix, such as the short IDs shown by 'docs list'.`,
 	Example: `  manuals docs get abc12345
   manuals documents get abc12345 -o json`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id := args[0]
-
-		doc, err := apiClient.GetDocument(id)
+		doc, err := apiClient.ResolveDocument(cmd.Context(), args[0])
 		if err != nil {
 			return fmt.Errorf("failed to get document: %w", err)
 		}
 
-		if out.IsJSON() {
-			return out.JSON(doc)
+		if out.IsStructured() {
+			return out.Value(doc)
+		}
+		if out.IsDelimited() {
+			out.Table([]string{"ID", "FILENAME", "DEVICE", "PATH", "TYPE", "SIZE", "CHECKSUM", "INDEXED"},
+				[][]string{{doc.ID, doc.Filename, doc.DeviceID, doc.Path, doc.MimeType, docSize(doc.SizeBytes), doc.Checksum, doc.IndexedAt}})
+			return nil
 		}
 
 		out.Text("Document: %s\n", doc.Filename)
@@ -95,7 +216,7 @@ var documentsGetCmd = &cobra.Command{
 		out.Text("  Path:      %s\n", doc.Path)
 		out.Text("  Type:      %s\n", doc.MimeType)
 		out.Text("  Size:      %s\n", output.FormatSize(doc.SizeBytes))
-		out.Text("  Checksum:  %s\n", doc.Checksum[:16]+"...")
+		out.Text("  Checksum:  %s\n", output.Truncate(doc.Checksum, 19))
 		out.Text("  Indexed:   %s\n", doc.IndexedAt)
 
 		return nil
@@ -103,61 +224,426 @@ var documentsGetCmd = &cobra.Command{
 }
 
 var documentsDownloadCmd = &cobra.Command{
-	Use:   "download <id>",
-	Short: "Download a document",
-	Long: `Download a document file by ID.
+	Use:   "download <id>... | --device <id>",
+	Short: "Download documents",
+	Long: `Download one or more document files by ID or unique ID prefix.
 
 By default, saves to the current directory with the original filename.
-Use --output to specify a different path.`,
+Use --output to specify a different path. With several documents, --output
+names a directory, which is created if needed.
+
+Pass several IDs, "-" to read IDs from stdin (one per line), or --device
+to download every document of a device. Downloads run in parallel (see
+--concurrency) and a summary is printed at the end. Files that already
+exist with a matching checksum are skipped. Documents sharing a filename
+are saved with their short ID appended, and filenames that are not plain
+file names (such as ones containing "..") are refused.
+
+Data is written to a .part file that is renamed into place when complete.
+If a download is interrupted, rerun with --resume to continue from where
+it stopped. Existing files are not overwritten unless --force is given.
+
+The content is verified against the document checksum while it is
+written. Files that do not match are quarantined with a .corrupt suffix,
+and if every failed download was such a mismatch the exit code is 6. Use
+--no-verify to skip verification.`,
 	Example: `  manuals docs download abc12345
   manuals docs download abc12345 -o ~/Documents/datasheet.pdf
-  manuals documents download abc12345 --output ./docs/`,
-	Args: cobra.ExactArgs(1),
+  manuals documents download abc12345 --output ./docs/
+  manuals docs download abc12345 --resume
+  manuals docs download --device dev12345 -o ./datasheets/ --concurrency 8
+  manuals docs list --device dev12345 -o json | jq -r '.data[].id' | manuals docs download - -o ./docs/`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		id := args[0]
-
-		// Get document info first for the filename
-		doc, err := apiClient.GetDocument(id)
+		ids, err := downloadIDs(args)
 		if err != nil {
-			return fmt.Errorf("failed to get document info: %w", err)
+			return err
 		}
-
-		// Download the file
-		body, _, err := apiClient.DownloadDocument(id)
-		if err != nil {
-			return fmt.Errorf("failed to download document: %w", err)
+		if len(ids) == 0 && docsDownloadDevice == "" {
+			return &usageError{err: errors.New("no documents given: pass document IDs, \"-\" or --device"), cmdPath: cmd.CommandPath()}
 		}
-		defer body.Close()
 
-		// Determine output path
-		outputPath := docsOutput
-		if outputPath == "" {
-			outputPath = doc.Filename
-		} else {
-			// Check if output is a directory
-			info, err := os.Stat(outputPath)
-			if err == nil && info.IsDir() {
+		// A single document keeps the simple file-or-directory semantics.
+		if len(ids) == 1 && docsDownloadDevice == "" {
+			doc, err := apiClient.ResolveDocument(cmd.Context(), ids[0])
+			if err != nil {
+				return fmt.Errorf("failed to get document info: %w", err)
+			}
+
+			outputPath := docsOutput
+			if outputPath == "" || isDir(outputPath) {
+				if err := checkFilename(doc.Filename); err != nil {
+					return err
+				}
 				outputPath = filepath.Join(outputPath, doc.Filename)
 			}
+
+			res := downloadDocument(cmd.Context(), doc, outputPath, docsDownloadOptions())
+			if res.err != nil {
+				return res.err
+			}
+			if res.skipped {
+				out.Text("Skipped %s: %s is up to date\n", doc.Filename, outputPath)
+			} else {
+				out.Text("Downloaded %s (%s) to %s\n", doc.Filename, output.FormatSize(res.written), outputPath)
+			}
+			return nil
 		}
 
-		// Create output file
-		file, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
+		return downloadMany(cmd, ids)
+	},
+}
+
+// docSize formats a document size for a table, or as a byte count for
+// delimited output.
+func docSize(bytes int64) string {
+	if out.IsDelimited() {
+		return strconv.FormatInt(bytes, 10)
+	}
+	return output.FormatSize(bytes)
+}
+
+// downloadIDs returns the document IDs from args, reading them from stdin
+// when the only argument is "-".
+func downloadIDs(args []string) ([]string, error) {
+	if len(args) != 1 || args[0] != "-" {
+		return args, nil
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-		defer file.Close()
+		ids = append(ids, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IDs from stdin: %w", err)
+	}
+	return ids, nil
+}
 
-		// Copy content
-		written, err := io.Copy(file, body)
-		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
+// downloadResult is the outcome of downloading one document.
+type downloadResult struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Status   string `json:"status"`
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+
+	written int64
+	skipped bool
+	err     error
+}
+
+// downloadDocument downloads doc to outputPath, skipping it if an
+// up-to-date copy already exists.
+func downloadDocument(ctx context.Context, doc *client.Document, outputPath string, dl downloadOptions) downloadResult {
+	res := downloadResult{ID: doc.ID, Filename: doc.Filename, Path: outputPath}
+
+	opts := client.DownloadOptions{
+		Resume:    dl.resume,
+		Overwrite: dl.force,
+	}
+	if !dl.noVerify && doc.Checksum != "" {
+		if _, err := checksum.New(doc.Checksum); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not verifying %s: %v\n", doc.Filename, err)
+		} else {
+			opts.Checksum = doc.Checksum
+		}
+	}
+
+	// Skip files that are already present and intact.
+	if opts.Checksum != "" && !dl.force {
+		if err := checksum.File(outputPath, opts.Checksum); err == nil {
+			res.skipped = true
+			return res.finish()
 		}
+	}
+	if opts.Checksum == "" && dl.keepExisting && !dl.force {
+		if _, err := os.Stat(outputPath); err == nil {
+			res.skipped = true
+			return res.finish()
+		}
+	}
+
+	written, err := apiClient.DownloadFile(ctx, doc.ID, outputPath, opts)
+	res.written = written
+	switch {
+	case errors.Is(err, client.ErrExists):
+		res.err = fmt.Errorf("%s already exists (use --force to overwrite)", outputPath)
+	case err != nil:
+		if _, statErr := os.Stat(outputPath + ".part"); statErr == nil {
+			res.err = fmt.Errorf("failed to download document (rerun with --resume to continue): %w", err)
+		} else {
+			res.err = fmt.Errorf("failed to download document: %w", err)
+		}
+	}
+	return res.finish()
+}
+
+// finish fills in the exported fields used for JSON output.
+func (r downloadResult) finish() downloadResult {
+	r.Bytes = r.written
+	switch {
+	case r.err != nil:
+		r.Status = "failed"
+		r.Error = r.err.Error()
+	case r.skipped:
+		r.Status = "skipped"
+	default:
+		r.Status = "downloaded"
+	}
+	return r
+}
+
+// downloadMany downloads several documents into the output directory using
+// a bounded pool of workers.
+func downloadMany(cmd *cobra.Command, ids []string) error {
+	ctx := cmd.Context()
 
-		out.Text("Downloaded %s (%s) to %s\n", doc.Filename, output.FormatSize(written), outputPath)
+	dir := docsOutput
+	if dir == "" {
+		dir = "."
+	}
 
+	// Documents listed for a device already carry their metadata; IDs
+	// given directly are looked up by the workers.
+	var docs []*client.Document
+	if docsDownloadDevice != "" {
+		deviceID, err := resolveDeviceID(ctx, docsDownloadDevice)
+		if err != nil {
+			return err
+		}
+		for d, err := range apiClient.AllDocuments(ctx, 0, deviceID) {
+			if err != nil {
+				return fmt.Errorf("failed to list documents: %w", err)
+			}
+			docs = append(docs, &d)
+		}
+	}
+	for _, id := range ids {
+		docs = append(docs, &client.Document{ID: id})
+	}
+	if len(docs) == 0 {
+		out.Println("No documents found.")
 		return nil
-	},
+	}
+
+	all, stats, err := downloadAll(ctx, docs, dir, docsDownloadOptions())
+	if err != nil {
+		return err
+	}
+
+	if out.IsStructured() {
+		if err := out.List(all, all); err != nil {
+			return err
+		}
+	} else {
+		out.Text("\n%s\n", stats)
+	}
+	return stats.err(ctx)
+}
+
+// downloadStats summarizes a batch of downloads.
+type downloadStats struct {
+	downloaded, skipped, failed, count int
+	bytes                              int64
+	dir                                string
+
+	// mismatched counts the failures that were checksum mismatches, the
+	// last of which is mismatch.
+	mismatched int
+	mismatch   *checksum.MismatchError
+}
+
+// String implements fmt.Stringer.
+func (s downloadStats) String() string {
+	return fmt.Sprintf("Downloaded %d, skipped %d, failed %d (%s) to %s", s.downloaded, s.skipped, s.failed, output.FormatSize(s.bytes), s.dir)
+}
+
+// err returns the error for the batch: cancellation, or the number of
+// failed downloads, wrapping a checksum mismatch if every failure is one.
+func (s downloadStats) err(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch {
+	case s.failed > 0 && s.mismatched == s.failed:
+		return fmt.Errorf("%d of %d downloads failed: %w", s.failed, s.count, s.mismatch)
+	case s.failed > 0:
+		return fmt.Errorf("%d of %d downloads failed", s.failed, s.count)
+	}
+	return nil
+}
+
+// downloadAll downloads docs into dir, which is created if needed, using a
+// bounded pool of opts.concurrency workers and reporting progress on
+// stderr. Documents without a filename are looked up first.
+func downloadAll(ctx context.Context, docs []*client.Document, dir string, opts downloadOptions) ([]downloadResult, downloadStats, error) {
+	stats := downloadStats{count: len(docs), dir: dir}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, stats, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	workers := max(min(opts.concurrency, len(docs)), 1)
+	jobs := make(chan *client.Document)
+	results := make(chan downloadResult)
+
+	// Documents with known filenames claim them in order, so which of two
+	// documents with the same name is renamed does not depend on timing.
+	var names fileNames
+	for _, doc := range docs {
+		if doc.Filename != "" {
+			_, _ = names.assign(doc)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for doc := range jobs {
+				if doc.Filename == "" {
+					d, err := apiClient.ResolveDocument(ctx, doc.ID)
+					if err != nil {
+						results <- downloadResult{ID: doc.ID, err: fmt.Errorf("failed to get document info: %w", err)}.finish()
+						continue
+					}
+					doc = d
+				}
+				name, dup, err := names.claim(doc)
+				switch {
+				case err != nil:
+					results <- downloadResult{ID: doc.ID, Filename: doc.Filename, err: err}.finish()
+				case dup:
+					results <- downloadResult{ID: doc.ID, Filename: doc.Filename, Path: filepath.Join(dir, name), skipped: true}.finish()
+				default:
+					results <- downloadDocument(ctx, doc, filepath.Join(dir, name), opts)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, doc := range docs {
+			select {
+			case jobs <- doc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []downloadResult
+	for res := range results {
+		all = append(all, res)
+		name := res.Filename
+		if name == "" {
+			name = res.ID
+		}
+		switch {
+		case res.err != nil:
+			stats.failed++
+			if errors.As(res.err, &stats.mismatch) {
+				stats.mismatched++
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] failed     %s: %v\n", len(all), len(docs), name, res.err)
+		case res.skipped:
+			stats.skipped++
+			fmt.Fprintf(os.Stderr, "[%d/%d] up to date %s\n", len(all), len(docs), name)
+		default:
+			stats.downloaded++
+			stats.bytes += res.written
+			fmt.Fprintf(os.Stderr, "[%d/%d] downloaded %s (%s, %s total)\n", len(all), len(docs), name, output.FormatSize(res.written), output.FormatSize(stats.bytes))
+		}
+	}
+	return all, stats, nil
+}
+
+// fileNames assigns the documents of a batch distinct file names, so no two
+// downloads write the same file.
+type fileNames struct {
+	mu sync.Mutex
+
+	// taken maps assigned names, lowercased for case-insensitive file
+	// systems, to the ID of their document.
+	taken map[string]string
+
+	// names maps document IDs to their names.
+	names map[string]string
+
+	// started holds the IDs of documents being downloaded.
+	started map[string]bool
+}
+
+// assign returns the file name for doc: its filename or, if another
+// document already has that name, the filename with doc's short ID added
+// before the extension.
+func (n *fileNames) assign(doc *client.Document) (string, error) {
+	if err := checkFilename(doc.Filename); err != nil {
+		return "", err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.assignLocked(doc), nil
+}
+
+// assignLocked is assign with n.mu held.
+func (n *fileNames) assignLocked(doc *client.Document) string {
+	if name, ok := n.names[doc.ID]; ok {
+		return name
+	}
+	if n.taken == nil {
+		n.taken, n.names, n.started = map[string]string{}, map[string]string{}, map[string]bool{}
+	}
+	name := doc.Filename
+	if _, ok := n.taken[strings.ToLower(name)]; ok {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + output.ShortIDCell(doc.ID) + ext
+	}
+	n.taken[strings.ToLower(name)] = doc.ID
+	n.names[doc.ID] = name
+	return name
+}
+
+// claim assigns doc its file name, as assign does, for downloading. dup
+// reports that doc was claimed before, as when an ID is given twice.
+func (n *fileNames) claim(doc *client.Document) (name string, dup bool, err error) {
+	if err := checkFilename(doc.Filename); err != nil {
+		return "", false, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	name = n.assignLocked(doc)
+	dup = n.started[doc.ID]
+	n.started[doc.ID] = true
+	return name, dup, nil
+}
+
+// checkFilename returns an error if name, a filename given by the server,
+// is not safe to save a document under.
+func checkFilename(name string) error {
+	if err := safename.Check(name); err != nil {
+		return fmt.Errorf("refusing to save document: %w", err)
+	}
+	return nil
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
 
 func init() {
@@ -168,7 +654,15 @@ func init() {
 
 	documentsListCmd.Flags().IntVarP(&docsLimit, "limit", "l", 50, "maximum number of results")
 	documentsListCmd.Flags().IntVar(&docsOffset, "offset", 0, "offset for pagination")
-	documentsListCmd.Flags().StringVar(&docsDeviceID, "device", "", "filter by device ID")
+	documentsListCmd.Flags().StringVar(&docsDeviceID, "device", "", "filter by device ID, name or path")
+	documentsListCmd.Flags().BoolVar(&docsAll, "all", false, "fetch all pages (--limit sets the page size)")
+	documentsListCmd.MarkFlagsMutuallyExclusive("all", "offset")
+	addTableFlags(documentsListCmd)
 
 	documentsDownloadCmd.Flags().StringVarP(&docsOutput, "output", "o", "", "output path (file or directory)")
+	documentsDownloadCmd.Flags().StringVar(&docsDownloadDevice, "device", "", "download all documents for a device (ID, name or path)")
+	documentsDownloadCmd.Flags().IntVarP(&docsConcurrency, "concurrency", "j", defaultConcurrency, "number of parallel downloads")
+	documentsDownloadCmd.Flags().BoolVar(&docsResume, "resume", false, "resume an interrupted download from its .part file")
+	documentsDownloadCmd.Flags().BoolVarP(&docsForce, "force", "f", false, "overwrite an existing file")
+	documentsDownloadCmd.Flags().BoolVar(&docsNoVerify, "no-verify", false, "skip checksum verification")
 }
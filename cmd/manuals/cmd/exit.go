@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by Execute. See Execute for the full table.
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitUsage       = 2
+	ExitAuth        = 3
+	ExitNotFound    = 4
+	ExitNetwork     = 5
+	ExitChecksum    = 6
+	ExitInterrupted = 130
+)
+
+// usageError marks errors caused by invalid command-line usage.
+type usageError struct {
+	err     error
+	cmdPath string
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// exitCode maps an error to its exit code.
+func exitCode(err error) int {
+	var usageErr *usageError
+	var mismatchErr *checksum.MismatchError
+	var ambiguousErr *client.AmbiguousError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.As(err, &usageErr), errors.As(err, &ambiguousErr), isCobraUsageError(err):
+		return ExitUsage
+	case client.IsUnauthorized(err), client.IsForbidden(err):
+		return ExitAuth
+	case client.IsNotFound(err):
+		return ExitNotFound
+	case client.IsNetwork(err), client.IsServerError(err), errors.Is(err, client.ErrOffline):
+		return ExitNetwork
+	case errors.As(err, &mismatchErr):
+		return ExitChecksum
+	default:
+		return ExitError
+	}
+}
+
+// isCobraUsageError detects usage errors that cobra reports as plain
+// errors before any hook of ours runs.
+func isCobraUsageError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "unknown command") ||
+		strings.HasPrefix(msg, "required flag")
+}
+
+// errorEnvelope is the JSON error document written to stderr when the
+// output format is json.
+type errorEnvelope struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Message   string `json:"message"`
+	ExitCode  int    `json:"exit_code"`
+	Status    int    `json:"status,omitempty"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Candidates lists the matches of an ambiguous ID prefix.
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// printError writes err to w as text or, in JSON mode, as an envelope.
+func printError(w io.Writer, err error, code int, asJSON bool) {
+	if !asJSON {
+		fmt.Fprintln(w, "Error:", err)
+		var ambiguousErr *client.AmbiguousError
+		if code == ExitUsage && !errors.As(err, &ambiguousErr) {
+			cmdPath := rootCmd.Name()
+			var usageErr *usageError
+			if errors.As(err, &usageErr) {
+				cmdPath = usageErr.cmdPath
+			}
+			fmt.Fprintf(w, "Run '%s --help' for usage.\n", cmdPath)
+		}
+		return
+	}
+
+	detail := errorDetail{
+		Message:  err.Error(),
+		ExitCode: code,
+	}
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		detail.Status = apiErr.StatusCode
+		detail.Code = apiErr.Code
+		detail.RequestID = apiErr.RequestID
+	}
+	var ambiguousErr *client.AmbiguousError
+	if errors.As(err, &ambiguousErr) {
+		detail.Candidates = ambiguousErr.Candidates
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(errorEnvelope{Error: detail})
+}
+
+// markUsageErrors wraps flag and argument validation errors of cmd and its
+// children as usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &usageError{err: err, cmdPath: c.CommandPath()}
+	})
+
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			if err := args(c, a); err != nil {
+				return &usageError{err: err, cmdPath: c.CommandPath()}
+			}
+			return nil
+		}
+	}
+
+	for _, child := range cmd.Commands() {
+		markUsageErrors(child)
+	}
+}
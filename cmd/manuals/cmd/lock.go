@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/lockfile"
+	"github.com/rmrfslashbin/manuals-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockPath        string
+	lockAddDevice   string
+	lockDir         string
+	lockConcurrency int
+	lockForce       bool
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <doc-id>... | --device <id>",
+	Short: "Pin documents in the project lockfile",
+	Long: `Record documents in the project's manuals.lock, pinning each by ID,
+filename, device and checksum.
+
+The lockfile is looked for in the current directory and its parents, and
+created in the current directory if there is none. Adding a document that
+is already pinned updates its entry to the server's current version,
+bypassing any cached copy.
+
+Pass document IDs or unique ID prefixes, "-" to read IDs from stdin, or
+--device to pin every document of a device.`,
+	Example: `  manuals add d0c00001
+  manuals add --device "ESP32-S3-DevKitC-1"
+  manuals search --documents "bme280" -o json | jq -r '.results[0].id' | manuals add -`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		ids, err := downloadIDs(args)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 && lockAddDevice == "" {
+			return &usageError{err: errors.New("no documents given: pass document IDs, \"-\" or --device"), cmdPath: cmd.CommandPath()}
+		}
+
+		lf, err := openLockfile(true)
+		if err != nil {
+			return err
+		}
+
+		var docs []*client.Document
+		if lockAddDevice != "" {
+			deviceID, err := resolveDeviceID(ctx, lockAddDevice)
+			if err != nil {
+				return err
+			}
+			for d, err := range lockClient().AllDocuments(ctx, 0, deviceID) {
+				if err != nil {
+					return fmt.Errorf("failed to list documents: %w", err)
+				}
+				docs = append(docs, &d)
+			}
+		}
+		for _, id := range ids {
+			doc, err := lockClient().ResolveDocument(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to get document %s: %w", id, err)
+			}
+			docs = append(docs, doc)
+		}
+
+		// Device names make the lockfile readable; they are optional.
+		deviceNames := map[string]string{}
+		var entries []lockfile.Entry
+		for _, doc := range docs {
+			name, ok := deviceNames[doc.DeviceID]
+			if !ok {
+				if d, err := apiClient.GetDeviceContext(ctx, doc.DeviceID); err == nil {
+					name = d.Name
+				}
+				deviceNames[doc.DeviceID] = name
+			}
+
+			if doc.Checksum == "" {
+				fmt.Fprintf(os.Stderr, "Warning: %s has no checksum; 'manuals install' cannot verify it\n", doc.Filename)
+			}
+			entry := lockfile.NewEntry(doc, name)
+			_, existed := lf.Lookup(entry.ID)
+			updated := lf.Put(entry)
+			entries = append(entries, entry)
+
+			if out.IsHuman() {
+				switch {
+				case updated:
+					out.Text("Updated %s (%s)\n", entry.Filename, out.ShortID(entry.ID))
+				case existed:
+					out.Text("Unchanged %s (%s)\n", entry.Filename, out.ShortID(entry.ID))
+				default:
+					out.Text("Added %s (%s)\n", entry.Filename, out.ShortID(entry.ID))
+				}
+			}
+		}
+
+		if err := lf.Save(); err != nil {
+			return fmt.Errorf("failed to write lockfile: %w", err)
+		}
+		if out.IsStructured() {
+			return out.List(lf, entries)
+		}
+		if out.IsHuman() {
+			out.Text("%d documents pinned in %s\n", len(lf.Documents), lf.Path())
+		}
+		return nil
+	},
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Download the documents pinned in the project lockfile",
+	Long: `Download every document in the project's manuals.lock into the
+project's docs/ directory (next to the lockfile, or the lockfile's dir
+setting; --dir overrides both). The lockfile's dir must stay inside the
+project, so a checked-out lockfile cannot write elsewhere; only --dir can
+point outside it.
+
+Each document is verified against the checksum in the lockfile, not the
+server's current one, so a document that changed since it was pinned
+fails with exit code 6 and is kept as <file>.corrupt. Run 'manuals
+outdated' to see what changed and 'manuals add <id>' to accept it. Files
+already present with the pinned checksum are skipped, as are existing
+files of documents pinned without a checksum.`,
+	Example: `  manuals install
+  manuals install --dir ./third_party/datasheets
+  manuals install --lockfile ../firmware/manuals.lock`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		lf, err := openLockfile(false)
+		if err != nil {
+			return err
+		}
+		if len(lf.Documents) == 0 {
+			out.Text("No documents in %s.\n", lf.Path())
+			return nil
+		}
+
+		dir := lockDir
+		if dir == "" {
+			dir = lf.InstallDir()
+		}
+		docs := make([]*client.Document, len(lf.Documents))
+		for i, e := range lf.Documents {
+			docs[i] = e.Document()
+		}
+		opts := downloadOptions{
+			concurrency:  lockConcurrency,
+			force:        lockForce,
+			keepExisting: true,
+		}
+
+		all, stats, err := downloadAll(ctx, docs, dir, opts)
+		if err != nil {
+			return err
+		}
+		if out.IsStructured() {
+			if err := out.List(all, all); err != nil {
+				return err
+			}
+		} else {
+			out.Text("\n%s\n", stats)
+		}
+
+		// Report documents that no longer match the lockfile as checksum
+		// failures.
+		var mismatch *checksum.MismatchError
+		changed := 0
+		for _, res := range all {
+			var m *checksum.MismatchError
+			if errors.As(res.err, &m) {
+				changed++
+				mismatch = m
+			}
+		}
+		if changed > 0 && ctx.Err() == nil {
+			return fmt.Errorf("%d of %d documents do not match %s (see 'manuals outdated'): %w", changed, len(docs), lf.Path(), mismatch)
+		}
+		return stats.err(ctx)
+	},
+}
+
+// outdatedEntry describes a locked document that differs from the server.
+type outdatedEntry struct {
+	ID               string `json:"id"`
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	LockedChecksum   string `json:"locked_checksum"`
+	CurrentChecksum  string `json:"current_checksum,omitempty"`
+	LockedIndexedAt  string `json:"locked_indexed_at"`
+	CurrentIndexedAt string `json:"current_indexed_at,omitempty"`
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Report pinned documents that changed on the server",
+	Long: `Compare each document in the project's manuals.lock with the server.
+Cached responses are revalidated, so changes show up at once.
+
+A document is "changed" if its checksum differs, "reindexed" if only its
+indexed time differs, and "removed" if it no longer exists. The command
+exits with status 1 if any document is outdated, so it can gate CI; run
+'manuals add <id>' to pin the current version.`,
+	Example: `  manuals outdated
+  manuals outdated -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		lf, err := openLockfile(false)
+		if err != nil {
+			return err
+		}
+
+		api := lockClient()
+		var outdated []outdatedEntry
+		for _, e := range lf.Documents {
+			o := outdatedEntry{
+				ID:              e.ID,
+				Filename:        e.Filename,
+				LockedChecksum:  e.Checksum,
+				LockedIndexedAt: e.IndexedAt,
+			}
+			doc, err := api.GetDocumentContext(ctx, e.ID)
+			switch {
+			case client.IsNotFound(err):
+				o.Status = "removed"
+			case err != nil:
+				return fmt.Errorf("failed to get document %s: %w", e.ID, err)
+			case doc.Checksum != e.Checksum:
+				o.Status = "changed"
+			case doc.IndexedAt != e.IndexedAt:
+				o.Status = "reindexed"
+			default:
+				continue
+			}
+			if doc != nil {
+				o.CurrentChecksum, o.CurrentIndexedAt = doc.Checksum, doc.IndexedAt
+			}
+			outdated = append(outdated, o)
+		}
+
+		if out.IsStructured() {
+			if outdated == nil {
+				outdated = []outdatedEntry{}
+			}
+			if err := out.List(outdated, outdated); err != nil {
+				return err
+			}
+		} else if len(outdated) == 0 {
+			if out.IsHuman() {
+				out.Text("All %d documents in %s are up to date.\n", len(lf.Documents), lf.Path())
+			}
+		} else {
+			rows := make([][]string, len(outdated))
+			for i, o := range outdated {
+				rows[i] = []string{
+					out.ShortID(o.ID), o.Filename, o.Status,
+					o.LockedIndexedAt, o.CurrentIndexedAt,
+					lockChecksum(o.LockedChecksum), lockChecksum(o.CurrentChecksum),
+				}
+			}
+			out.Table([]string{"ID", "FILENAME", "STATUS", "LOCKED", "CURRENT", "LOCKED CHECKSUM", "CURRENT CHECKSUM"}, rows)
+		}
+
+		if len(outdated) > 0 {
+			return fmt.Errorf("%d of %d documents in %s are outdated", len(outdated), len(lf.Documents), lf.Path())
+		}
+		return nil
+	},
+}
+
+// lockClient returns the API client for reading documents to pin or
+// compare with the lockfile. It revalidates cached responses, so checksums
+// are never older than the server's.
+func lockClient() *client.Client {
+	return apiClient.With(client.WithRevalidate(true))
+}
+
+// lockChecksum shortens a checksum for a table; delimited output keeps it
+// whole.
+func lockChecksum(sum string) string {
+	if out.IsDelimited() {
+		return sum
+	}
+	return output.Truncate(sum, 19)
+}
+
+// openLockfile loads the lockfile named by --lockfile, or else the one in
+// the current directory or its nearest parent. With create, a missing
+// lockfile is started in the current directory (or at --lockfile).
+func openLockfile(create bool) (*lockfile.File, error) {
+	path := lockPath
+	if path == "" {
+		found, err := lockfile.Find(".")
+		switch {
+		case err == nil:
+			path = found
+		case errors.Is(err, lockfile.ErrNotFound) && create:
+			path = lockfile.Name
+		default:
+			return nil, err
+		}
+	}
+
+	lf, err := lockfile.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if create {
+			return lockfile.New(path), nil
+		}
+		return nil, fmt.Errorf("no lockfile at %s (run 'manuals add' to create one)", path)
+	}
+	return lf, err
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(installCmd)
+	rootCmd.AddCommand(outdatedCmd)
+
+	for _, c := range []*cobra.Command{addCmd, installCmd, outdatedCmd} {
+		c.Flags().StringVar(&lockPath, "lockfile", "", "lockfile to use (default: "+lockfile.Name+" in the current directory or a parent)")
+	}
+	addCmd.Flags().StringVar(&lockAddDevice, "device", "", "pin all documents of a device (ID, name or path)")
+	installCmd.Flags().StringVar(&lockDir, "dir", "", "directory to install into (default: docs/ next to the lockfile)")
+	installCmd.Flags().IntVarP(&lockConcurrency, "concurrency", "j", defaultConcurrency, "number of parallel downloads")
+	installCmd.Flags().BoolVarP(&lockForce, "force", "f", false, "overwrite existing files")
+}
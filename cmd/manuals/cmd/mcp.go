@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+// mcpContentURI is the URI template of the device content resource.
+const mcpContentURI = "manuals://devices/{id}/content"
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve the Manuals API to LLM assistants",
+	Long:  `Serve the Manuals API to LLM assistants over the Model Context Protocol (MCP).`,
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an MCP server over stdio",
+	Long: `Run a Model Context Protocol server on stdin and stdout, for LLM
+assistants that launch it as a local tool server.
+
+The server offers these tools, backed by the same API client, config
+file, profile, API key, cache and timeouts as the other commands:
+
+  search          search devices, or documents with "documents": true
+  get_device      get a device by ID, ID prefix, name or path
+  list_documents  list documents, optionally of one device
+  get_document    get a document by ID or ID prefix
+
+Device content (Markdown) is also served as the resource
+manuals://devices/{id}/content.
+
+Messages are newline-delimited JSON-RPC 2.0; the server exits when stdin
+is closed. Nothing but protocol messages is written to stdout; --debug
+logs requests to stderr.`,
+	Example: `  manuals mcp serve
+  manuals --profile staging mcp serve
+
+  # Assistant configuration (e.g. mcpServers in its settings):
+  #   "manuals": {"command": "manuals", "args": ["mcp", "serve"]}
+
+  # Try it against a local server
+  echo '{"jsonrpc":"2.0","id":1,"method":"tools/list"}' |
+    manuals --api-url http://localhost:8080 mcp serve`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v := version
+		if v == "" {
+			v = "dev"
+		}
+		s := mcp.NewServer("manuals", v)
+		s.Instructions = `Look up hardware and software documentation in the Manuals database. ` +
+			`Use search to find devices (or documents), get_device for a device's details ` +
+			`and content, and list_documents or get_document for its datasheets and manuals.`
+		addMCPTools(s)
+		return s.Serve(cmd.Context(), os.Stdin, os.Stdout)
+	},
+}
+
+// addMCPTools adds the tools and resources of the Manuals API to s.
+func addMCPTools(s *mcp.Server) {
+	s.AddTool(mcp.Tool{
+		Name: "search",
+		Description: "Search the Manuals database for devices matching a query, ranked by relevance, " +
+			"with a snippet of each. With documents set, search for documents (datasheets, manuals) instead.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "query": {"type": "string", "description": "search query"},
+    "limit": {"type": "integer", "description": "maximum number of results (default 10)"},
+    "domain": {"type": "string", "description": "only devices in this domain (hardware, software)"},
+    "type": {"type": "string", "description": "only devices of this type"},
+    "min_score": {"type": "number", "description": "only results scoring at least this"},
+    "where": {"type": "array", "items": {"type": "string"}, "description": "device metadata predicates, e.g. vendor=espressif or pins>=30; all must match"},
+    "documents": {"type": "boolean", "description": "search documents instead of devices"}
+  },
+  "required": ["query"]
+}`),
+		Handler: mcpSearch,
+	})
+	s.AddTool(mcp.Tool{
+		Name: "get_device",
+		Description: "Get a device by ID, ID prefix, name or path. The device's Markdown content is included " +
+			"with include_content, or can be read from its content_uri resource.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "string", "description": "device ID, ID prefix, name or path"},
+    "include_content": {"type": "boolean", "description": "include the device's Markdown content"}
+  },
+  "required": ["id"]
+}`),
+		Handler: mcpGetDevice,
+	})
+	s.AddTool(mcp.Tool{
+		Name:        "list_documents",
+		Description: "List documents (datasheets, manuals) with their filename, type, size and device, optionally of one device.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "device": {"type": "string", "description": "only documents of this device (ID, ID prefix, name or path)"},
+    "limit": {"type": "integer", "description": "maximum number of results (default 50)"},
+    "offset": {"type": "integer", "description": "offset for pagination"}
+  }
+}`),
+		Handler: mcpListDocuments,
+	})
+	s.AddTool(mcp.Tool{
+		Name:        "get_document",
+		Description: "Get a document's details by ID or ID prefix.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "string", "description": "document ID or ID prefix"}
+  },
+  "required": ["id"]
+}`),
+		Handler: mcpGetDocument,
+	})
+
+	s.AddResourceTemplate(mcp.ResourceTemplate{
+		URITemplate: mcpContentURI,
+		Name:        "device-content",
+		Description: "The Markdown content of a device, by ID, ID prefix, name or path",
+		MIMEType:    "text/markdown",
+		Read: func(ctx context.Context, uri string, vars map[string]string) (*mcp.ResourceContents, error) {
+			device, err := apiClient.ResolveDevice(ctx, vars["id"])
+			if client.IsNotFound(err) {
+				return nil, &mcp.Error{Code: mcp.CodeResourceNotFound, Message: fmt.Sprintf("device %s not found", vars["id"])}
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.ResourceContents{URI: uri, MIMEType: "text/markdown", Text: device.Content}, nil
+		},
+	})
+}
+
+// mcpSearch implements the search tool: a device search, or a document
+// search with documents set, narrowed by the same filters as 'manuals
+// search'.
+func mcpSearch(ctx context.Context, raw json.RawMessage) (*mcp.ToolResult, error) {
+	var args struct {
+		Query     string   `json:"query"`
+		Limit     int      `json:"limit"`
+		Domain    string   `json:"domain"`
+		Type      string   `json:"type"`
+		MinScore  float64  `json:"min_score"`
+		Where     []string `json:"where"`
+		Documents bool     `json:"documents"`
+	}
+	if err := mcp.DecodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	if args.Limit <= 0 {
+		args.Limit = 10
+	}
+
+	filter := client.SearchFilter{Domain: args.Domain, Type: args.Type, MinScore: args.MinScore}
+	for _, w := range args.Where {
+		p, err := client.ParsePredicate(w)
+		if err != nil {
+			return nil, err
+		}
+		filter.Where = append(filter.Where, p)
+	}
+
+	if args.Documents {
+		resp, err := apiClient.SearchDocumentsContext(ctx, args.Query, args.Limit, filter)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		return mcp.JSONResult(resp)
+	}
+	resp, err := apiClient.SearchFilteredContext(ctx, args.Query, args.Limit, filter)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	return mcp.JSONResult(resp)
+}
+
+// mcpGetDevice implements the get_device tool. The device's content is
+// left out unless include_content is set; content_uri names the resource
+// that serves it.
+func mcpGetDevice(ctx context.Context, raw json.RawMessage) (*mcp.ToolResult, error) {
+	var args struct {
+		ID             string `json:"id"`
+		IncludeContent bool   `json:"include_content"`
+	}
+	if err := mcp.DecodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.ID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	device, err := apiClient.ResolveDevice(ctx, args.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	result := struct {
+		*client.Device
+		ContentURI string `json:"content_uri"`
+	}{device, strings.Replace(mcpContentURI, "{id}", url.PathEscape(device.ID), 1)}
+	if !args.IncludeContent {
+		device.Content = ""
+	}
+	return mcp.JSONResult(result)
+}
+
+// mcpListDocuments implements the list_documents tool, listing one page of
+// documents, optionally of one device.
+func mcpListDocuments(ctx context.Context, raw json.RawMessage) (*mcp.ToolResult, error) {
+	var args struct {
+		Device string `json:"device"`
+		Limit  int    `json:"limit"`
+		Offset int    `json:"offset"`
+	}
+	if err := mcp.DecodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Limit <= 0 {
+		args.Limit = 50
+	}
+
+	deviceID, err := resolveDeviceID(ctx, args.Device)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient.ListDocumentsContext(ctx, args.Limit, args.Offset, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return mcp.JSONResult(resp)
+}
+
+// mcpGetDocument implements the get_document tool.
+func mcpGetDocument(ctx context.Context, raw json.RawMessage) (*mcp.ToolResult, error) {
+	var args struct {
+		ID string `json:"id"`
+	}
+	if err := mcp.DecodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.ID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	doc, err := apiClient.ResolveDocument(ctx, args.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return mcp.JSONResult(doc)
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.AddCommand(mcpServeCmd)
+}
@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/mcp"
+)
+
+// mcpTestDevices and mcpTestDocuments are served by newMCPTestAPI.
+var (
+	mcpTestDevices = []client.Device{
+		{
+			ID: "abc12345aaaa", Domain: "hardware", Type: "dev-boards", Name: "ESP32 DevKit",
+			Path: "hardware/dev-boards/esp32", Content: "# ESP32 DevKit\n\nA Wi-Fi board.",
+			Metadata: map[string]interface{}{"vendor": "espressif", "pins": 38},
+		},
+		{
+			ID: "def67890bbbb", Domain: "hardware", Type: "sensors", Name: "BME280",
+			Path: "hardware/sensors/bme280", Content: "# BME280\n\nA pressure sensor.",
+			Metadata: map[string]interface{}{"vendor": "bosch", "pins": 8},
+		},
+	}
+	mcpTestDocuments = []client.Document{
+		{ID: "d0c00001aaaa", DeviceID: "abc12345aaaa", Filename: "esp32.pdf", MimeType: "application/pdf", SizeBytes: 1000},
+		{ID: "d0c00002bbbb", DeviceID: "def67890bbbb", Filename: "bme280.pdf", MimeType: "application/pdf", SizeBytes: 2000},
+	}
+)
+
+// newMCPTestAPI points apiClient at a fake Manuals API serving the test
+// devices and documents, restoring it when the test ends.
+func newMCPTestAPI(t *testing.T) {
+	t.Helper()
+	prefix := "/api/" + client.APIVersion
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	notFound := func(w http.ResponseWriter, what string) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, client.ErrorResponse{Error: what + " not found"})
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		switch {
+		case path == "/search":
+			resp := client.SearchResponse{Query: q.Get("q"), Results: []client.SearchResult{}}
+			for _, d := range mcpTestDevices {
+				if strings.Contains(strings.ToLower(d.Name), strings.ToLower(q.Get("q"))) {
+					resp.Results = append(resp.Results, client.SearchResult{
+						DeviceID: d.ID, Name: d.Name, Domain: d.Domain, Type: d.Type, Path: d.Path, Score: 0.9,
+					})
+				}
+			}
+			resp.Total = len(resp.Results)
+			if n, err := strconv.Atoi(q.Get("limit")); err == nil && n < len(resp.Results) {
+				resp.Results = resp.Results[:n]
+			}
+			writeJSON(w, resp)
+
+		case path == "/search/documents":
+			resp := client.DocumentSearchResponse{Query: q.Get("q"), Results: []client.DocumentSearchResult{}}
+			for _, d := range mcpTestDocuments {
+				if strings.Contains(d.Filename, q.Get("q")) {
+					resp.Results = append(resp.Results, client.DocumentSearchResult{Document: d, Score: 0.8})
+				}
+			}
+			resp.Total = len(resp.Results)
+			writeJSON(w, resp)
+
+		case path == "/devices":
+			devices := make([]client.Device, len(mcpTestDevices))
+			for i, d := range mcpTestDevices {
+				d.Content = "" // lists omit content
+				devices[i] = d
+			}
+			if q.Get("offset") != "" {
+				devices = nil
+			}
+			writeJSON(w, client.DevicesResponse{Data: devices, Total: len(mcpTestDevices)})
+
+		case strings.HasPrefix(path, "/devices/"):
+			id := strings.TrimPrefix(path, "/devices/")
+			i := slices.IndexFunc(mcpTestDevices, func(d client.Device) bool { return d.ID == id })
+			if i < 0 {
+				notFound(w, "device")
+				return
+			}
+			writeJSON(w, mcpTestDevices[i])
+
+		case path == "/documents":
+			docs := []client.Document{}
+			for _, d := range mcpTestDocuments {
+				if q.Get("device_id") == "" || d.DeviceID == q.Get("device_id") {
+					docs = append(docs, d)
+				}
+			}
+			if q.Get("offset") != "" {
+				docs = nil
+			}
+			writeJSON(w, client.DocumentsResponse{Data: docs, Total: len(docs)})
+
+		case strings.HasPrefix(path, "/documents/"):
+			id := strings.TrimPrefix(path, "/documents/")
+			i := slices.IndexFunc(mcpTestDocuments, func(d client.Document) bool { return d.ID == id })
+			if i < 0 {
+				notFound(w, "document")
+				return
+			}
+			writeJSON(w, mcpTestDocuments[i])
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	saved := apiClient
+	apiClient = client.New(srv.URL, "test-key")
+	t.Cleanup(func() { apiClient = saved })
+}
+
+// mcpResponse is a decoded JSON-RPC response.
+type mcpResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *mcp.Error      `json:"error"`
+}
+
+// mcpRequest sends a request to an MCP server with the manuals tools and
+// returns the response.
+func mcpRequest(t *testing.T, method string, params interface{}) mcpResponse {
+	t.Helper()
+	s := mcp.NewServer("manuals", "test")
+	addMCPTools(s)
+
+	req, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), bytes.NewReader(append(req, '\n')), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var resp mcpResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response %q: %v", out.String(), err)
+	}
+	return resp
+}
+
+// mcpCallTool calls a tool, returning its result and whether it is an
+// error result.
+func mcpCallTool(t *testing.T, name string, args interface{}) (string, json.RawMessage, bool) {
+	t.Helper()
+	resp := mcpRequest(t, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+	if resp.Error != nil {
+		t.Fatalf("tools/call %s: error %d %s", name, resp.Error.Code, resp.Error.Message)
+	}
+	var result struct {
+		Content []mcp.Content `json:"content"`
+		// StructuredContent is kept raw for decoding into the expected type.
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 {
+		t.Fatalf("tools/call %s: %d content blocks, want 1", name, len(result.Content))
+	}
+	return result.Content[0].Text, result.StructuredContent, result.IsError
+}
+
+func TestMCPToolsList(t *testing.T) {
+	resp := mcpRequest(t, "tools/list", nil)
+	var result struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		var schema map[string]interface{}
+		if err := json.Unmarshal(tool.InputSchema, &schema); err != nil || schema["type"] != "object" {
+			t.Errorf("tool %s: invalid inputSchema %s", tool.Name, tool.InputSchema)
+		}
+	}
+	if got := strings.Join(names, ","); got != "search,get_device,list_documents,get_document" {
+		t.Errorf("tools = %s", got)
+	}
+}
+
+func TestMCPSearch(t *testing.T) {
+	newMCPTestAPI(t)
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		want      []string // device or document IDs
+		wantError string
+	}{
+		{"devices", map[string]interface{}{"query": "esp32"}, []string{"abc12345aaaa"}, ""},
+		{"no match", map[string]interface{}{"query": "nrf52"}, nil, ""},
+		{"where", map[string]interface{}{"query": "e", "where": []string{"vendor=bosch"}}, []string{"def67890bbbb"}, ""},
+		{"type", map[string]interface{}{"query": "e", "type": "dev-boards"}, []string{"abc12345aaaa"}, ""},
+		{"limit", map[string]interface{}{"query": "e", "limit": 1}, []string{"abc12345aaaa"}, ""},
+		{"documents", map[string]interface{}{"query": "bme", "documents": true}, []string{"d0c00002bbbb"}, ""},
+		{"missing query", map[string]interface{}{}, nil, "query is required"},
+		{"bad predicate", map[string]interface{}{"query": "e", "where": []string{"vendor"}}, nil, "invalid predicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, structured, isError := mcpCallTool(t, "search", tt.args)
+			if tt.wantError != "" {
+				if !isError || !strings.Contains(text, tt.wantError) {
+					t.Errorf("got %q (isError %v), want error containing %q", text, isError, tt.wantError)
+				}
+				return
+			}
+			if isError {
+				t.Fatalf("error result: %s", text)
+			}
+			var result struct {
+				Results []struct {
+					DeviceID string `json:"device_id"`
+					ID       string `json:"id"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal(structured, &result); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, r := range result.Results {
+				if tt.args["documents"] == true {
+					ids = append(ids, r.ID)
+				} else {
+					ids = append(ids, r.DeviceID)
+				}
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("results %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPGetDevice(t *testing.T) {
+	newMCPTestAPI(t)
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		wantID      string
+		wantContent string
+		wantError   string
+	}{
+		{"id", map[string]interface{}{"id": "abc12345aaaa"}, "abc12345aaaa", "", ""},
+		{"prefix", map[string]interface{}{"id": "def6"}, "def67890bbbb", "", ""},
+		{"name", map[string]interface{}{"id": "esp32 devkit"}, "abc12345aaaa", "", ""},
+		{"path", map[string]interface{}{"id": "hardware/sensors/bme280"}, "def67890bbbb", "", ""},
+		{"content", map[string]interface{}{"id": "BME280", "include_content": true}, "def67890bbbb", "# BME280\n\nA pressure sensor.", ""},
+		{"not found", map[string]interface{}{"id": "nope"}, "", "", "failed to get device"},
+		{"missing id", map[string]interface{}{}, "", "", "id is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, structured, isError := mcpCallTool(t, "get_device", tt.args)
+			if tt.wantError != "" {
+				if !isError || !strings.Contains(text, tt.wantError) {
+					t.Errorf("got %q (isError %v), want error containing %q", text, isError, tt.wantError)
+				}
+				return
+			}
+			if isError {
+				t.Fatalf("error result: %s", text)
+			}
+			var device struct {
+				ID         string `json:"id"`
+				Content    string `json:"content"`
+				ContentURI string `json:"content_uri"`
+			}
+			if err := json.Unmarshal(structured, &device); err != nil {
+				t.Fatal(err)
+			}
+			if device.ID != tt.wantID || device.Content != tt.wantContent {
+				t.Errorf("got device %s with content %q, want %s with %q", device.ID, device.Content, tt.wantID, tt.wantContent)
+			}
+			if want := "manuals://devices/" + tt.wantID + "/content"; device.ContentURI != want {
+				t.Errorf("content_uri = %q, want %q", device.ContentURI, want)
+			}
+		})
+	}
+}
+
+func TestMCPListDocuments(t *testing.T) {
+	newMCPTestAPI(t)
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		want      []string
+		wantError string
+	}{
+		{"all", map[string]interface{}{}, []string{"d0c00001aaaa", "d0c00002bbbb"}, ""},
+		{"device id", map[string]interface{}{"device": "abc12345aaaa"}, []string{"d0c00001aaaa"}, ""},
+		{"device name", map[string]interface{}{"device": "BME280"}, []string{"d0c00002bbbb"}, ""},
+		{"unknown device", map[string]interface{}{"device": "nope"}, nil, "failed to get device"},
+		{"bad arguments", map[string]interface{}{"limit": "ten"}, nil, "invalid arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, structured, isError := mcpCallTool(t, "list_documents", tt.args)
+			if tt.wantError != "" {
+				if !isError || !strings.Contains(text, tt.wantError) {
+					t.Errorf("got %q (isError %v), want error containing %q", text, isError, tt.wantError)
+				}
+				return
+			}
+			if isError {
+				t.Fatalf("error result: %s", text)
+			}
+			var resp client.DocumentsResponse
+			if err := json.Unmarshal(structured, &resp); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, d := range resp.Data {
+				ids = append(ids, d.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("documents %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPGetDocument(t *testing.T) {
+	newMCPTestAPI(t)
+	tests := []struct {
+		name, id  string
+		want      string
+		wantError string
+	}{
+		{"id", "d0c00002bbbb", "bme280.pdf", ""},
+		{"prefix", "d0c00001", "esp32.pdf", ""},
+		{"ambiguous prefix", "d0c0", "", "failed to get document"},
+		{"not found", "ffff", "", "failed to get document"},
+		{"missing id", "", "", "id is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, structured, isError := mcpCallTool(t, "get_document", map[string]interface{}{"id": tt.id})
+			if tt.wantError != "" {
+				if !isError || !strings.Contains(text, tt.wantError) {
+					t.Errorf("got %q (isError %v), want error containing %q", text, isError, tt.wantError)
+				}
+				return
+			}
+			if isError {
+				t.Fatalf("error result: %s", text)
+			}
+			var doc client.Document
+			if err := json.Unmarshal(structured, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.Filename != tt.want {
+				t.Errorf("filename = %q, want %q", doc.Filename, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPDeviceContent(t *testing.T) {
+	newMCPTestAPI(t)
+
+	resp := mcpRequest(t, "resources/templates/list", nil)
+	if !strings.Contains(string(resp.Result), `"uriTemplate":"`+mcpContentURI+`"`) {
+		t.Errorf("resources/templates/list = %s", resp.Result)
+	}
+
+	tests := []struct {
+		name, uri string
+		want      string
+		wantCode  int
+	}{
+		{"id", "manuals://devices/abc12345aaaa/content", "# ESP32 DevKit\n\nA Wi-Fi board.", 0},
+		{"escaped name", "manuals://devices/ESP32%20DevKit/content", "# ESP32 DevKit\n\nA Wi-Fi board.", 0},
+		{"escaped path", "manuals://devices/hardware%2Fsensors%2Fbme280/content", "# BME280\n\nA pressure sensor.", 0},
+		{"not found", "manuals://devices/nope/content", "", mcp.CodeResourceNotFound},
+		{"no template", "manuals://devices/abc12345aaaa", "", mcp.CodeResourceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := mcpRequest(t, "resources/read", map[string]string{"uri": tt.uri})
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("got %s, %+v; want error %d", resp.Result, resp.Error, tt.wantCode)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("error %d %s", resp.Error.Code, resp.Error.Message)
+			}
+			var result struct {
+				Contents []mcp.ResourceContents `json:"contents"`
+			}
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Contents) != 1 {
+				t.Fatalf("got %d contents, want 1", len(result.Contents))
+			}
+			c := result.Contents[0]
+			if c.URI != tt.uri || c.MIMEType != "text/markdown" || c.Text != tt.want {
+				t.Errorf("got %+v, want text %q", c, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/rmrfslashbin/manuals-cli/internal/cache"
 	"github.com/rmrfslashbin/manuals-cli/internal/client"
 	"github.com/rmrfslashbin/manuals-cli/internal/config"
 	"github.com/rmrfslashbin/manuals-cli/internal/output"
@@ -19,14 +25,21 @@ var (
 
 	// Global flags
 	cfgFile      string
+	profile      string
 	apiURL       string
 	apiKey       string
 	outputFormat string
+	timeout      time.Duration
+	retries      int
+	debug        bool
+	offline      bool
+	color        string
+	noPager      bool
 
 	// Global state
-	cfg    *config.Config
+	cfg       *config.Config
 	apiClient *client.Client
-	out    *output.Writer
+	out       *output.Writer
 )
 
 // SetVersionInfo sets the version information.
@@ -43,14 +56,27 @@ var rootCmd = &cobra.Command{
 	Long: `manuals is a command-line interface for searching and accessing
 hardware and software documentation from the Manuals platform.
 
-Configure the API endpoint and key via environment variables:
+Store the API key in the system keyring with 'manuals auth login', or
+configure the API endpoint and key via environment variables:
   MANUALS_API_URL  - API base URL (default: http://localhost:8080)
   MANUALS_API_KEY  - API key for authentication (required)
 
-Or create a config file at ~/.manuals.yaml:
+Or create a config file with 'manuals config init', or at ~/.manuals.yaml:
   api_url: http://manuals.local:8080
   api_key: your-api-key
-  output_format: table`,
+  output_format: table
+  timeout: 30s
+  retries: 2
+  cache_ttl: 5m
+
+Named profiles override the top-level settings; select one with
+--profile, MANUALS_PROFILE or 'manuals config use-profile':
+  current_profile: staging
+  profiles:
+    staging:
+      api_url: http://manuals-staging:8080
+      api_key: staging-key
+      output_format: json`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Skip initialization for version and help commands
 		if cmd.Name() == "version" || cmd.Name() == "help" {
@@ -59,10 +85,15 @@ Or create a config file at ~/.manuals.yaml:
 
 		// Load configuration
 		var err error
-		cfg, err = config.Load()
+		opts := loadOptions()
+		opts.AllowMissingProfile = hasAnnotation(cmd, annotationLenientProfile)
+		cfg, err = config.Load(opts)
 		if err != nil {
 			return err
 		}
+		for _, key := range cfg.Ignored {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s from %s: commands are only taken from your own config file\n", key, cfg.File)
+		}
 
 		// Override with flags
 		if apiURL != "" {
@@ -74,30 +105,190 @@ Or create a config file at ~/.manuals.yaml:
 		if outputFormat != "" {
 			cfg.OutputFormat = outputFormat
 		}
+		if cmd.Flags().Changed("timeout") {
+			cfg.Timeout = timeout
+		}
+		if cmd.Flags().Changed("retries") {
+			cfg.Retries = retries
+		}
+		if color != "" {
+			cfg.Color = color
+		}
 
-		// Validate
-		if err := cfg.Validate(); err != nil {
-			return err
+		out, err = output.Parse(cfg.OutputFormat)
+		if err != nil {
+			return &usageError{err: err, cmdPath: cmd.CommandPath()}
+		}
+		if err := out.SetColor(cfg.Color); err != nil {
+			return &usageError{err: err, cmdPath: cmd.CommandPath()}
+		}
+		if !noPager && out.IsHuman() {
+			out.StartPager(cfg.Pager)
+		}
+
+		if !needsClient(cmd) {
+			return nil
 		}
 
-		// Initialize client and output
-		apiClient = client.New(cfg.APIBaseURL, cfg.APIKey)
-		out = output.New(cfg.OutputFormat)
+		// Validate; cached responses can be served without a key
+		if !offline {
+			if _, err := resolveAPIKey(cmd.Context()); err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+		}
+
+		// Initialize client
+		clientOpts := []client.Option{
+			client.WithTimeout(cfg.Timeout),
+			client.WithRetry(client.RetryPolicy{
+				MaxAttempts: cfg.Retries + 1,
+				BaseDelay:   cfg.RetryWait,
+				MaxDelay:    cfg.RetryMaxWait,
+			}),
+		}
+		if debug {
+			clientOpts = append(clientOpts, client.WithDebug(os.Stderr))
+		}
+		if cfg.CacheDir != "" {
+			clientOpts = append(clientOpts, client.WithCache(cache.New(cfg.CacheDir, cfg.CacheTTL)))
+		}
+		if offline {
+			clientOpts = append(clientOpts, client.WithOffline(true))
+		}
+		apiClient = client.New(cfg.APIBaseURL, cfg.APIKey, clientOpts...)
 
 		return nil
 	},
 }
 
-// Execute runs the root command.
-func Execute() error {
-	return rootCmd.Execute()
+// loadOptions returns the config loading options given on the command line.
+func loadOptions() config.Options {
+	return config.Options{File: cfgFile, Profile: profile}
+}
+
+// Table flags of list commands.
+var (
+	tableColumns   []string
+	tableSortBy    string
+	tableReverse   bool
+	tableNoHeaders bool
+)
+
+// tableFlagsHelp describes the table flags in command help.
+const tableFlagsHelp = `Choose table columns with --columns and order rows with --sort-by (and
+--reverse); both take column names or JSON field names. Sorting --all
+output waits for the last page.`
+
+// addTableFlags adds the table flags to a list command.
+func addTableFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&tableColumns, "columns", nil, "comma-separated columns to show (names or JSON fields)")
+	cmd.Flags().StringVar(&tableSortBy, "sort-by", "", "sort rows by a column or JSON field")
+	cmd.Flags().BoolVar(&tableReverse, "reverse", false, "sort in descending order")
+	cmd.Flags().BoolVar(&tableNoHeaders, "no-headers", false, "omit the header line and summary text")
+}
+
+// tableOptions returns the table options given by the table flags.
+func tableOptions() output.TableOptions {
+	return output.TableOptions{
+		Columns:   tableColumns,
+		SortBy:    tableSortBy,
+		Reverse:   tableReverse,
+		NoHeaders: tableNoHeaders,
+	}
+}
+
+// annotationNoClient marks commands (and their subcommands) that work
+// without an API client, so no API key is required to run them.
+const annotationNoClient = "manuals/no-client"
+
+// annotationNoClientFlag names a boolean flag of a command that, when set,
+// makes the command work without an API client (e.g. search --local).
+const annotationNoClientFlag = "manuals/no-client-flag"
+
+// annotationLenientProfile marks commands (and their subcommands) that
+// run even if the selected profile is not defined, so it can be fixed or
+// created.
+const annotationLenientProfile = "manuals/lenient-profile"
+
+// needsClient reports whether cmd requires an API client.
+func needsClient(cmd *cobra.Command) bool {
+	if name, ok := cmd.Annotations[annotationNoClientFlag]; ok {
+		if set, err := cmd.Flags().GetBool(name); err == nil && set {
+			return false
+		}
+	}
+	return !hasAnnotation(cmd, annotationNoClient)
+}
+
+// hasAnnotation reports whether cmd or one of its parents has the
+// annotation key.
+func hasAnnotation(cmd *cobra.Command, key string) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the root command and returns the process exit code. SIGINT
+// and SIGTERM cancel the command context so in-flight requests and
+// downloads stop cleanly.
+//
+// Errors are printed to stderr, as a JSON envelope when the output format
+// is json. Exit codes are stable and may be relied on by scripts:
+//
+//	0    success
+//	1    general error
+//	2    usage error (unknown command, invalid flag or arguments, ambiguous ID)
+//	3    authentication or authorization failure (HTTP 401/403)
+//	4    resource not found (HTTP 404)
+//	5    network failure or server error (unreachable, timeout, HTTP 5xx)
+//	6    downloaded content failed checksum verification
+//	130  interrupted (SIGINT/SIGTERM)
+func Execute() int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	markUsageErrors(rootCmd)
+
+	err := rootCmd.ExecuteContext(ctx)
+	if out != nil {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		return ExitOK
+	}
+
+	code := exitCode(err)
+	asJSON := strings.EqualFold(outputFormat, "json")
+	if out != nil {
+		asJSON = out.IsJSON()
+	}
+	printError(os.Stderr, err, code, asJSON)
+
+	return code
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.manuals.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (env: MANUALS_CONFIG; default: ~/.manuals.yaml or ~/.config/manuals/config.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "config profile to use (env: MANUALS_PROFILE)")
 	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "API base URL")
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "API key")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format (table, json, text)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format: table, wide, json, text, yaml, csv, tsv, ndjson, markdown, template=<tmpl>, template-file=<path>, jsonpath=<expr>")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 2, "retries for transient request failures; 0 disables")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "print debug output (requests, retries) to stderr")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "serve responses from the local cache only")
+	rootCmd.PersistentFlags().StringVar(&color, "color", "", "colored output: auto, always or never (env: MANUALS_COLOR, NO_COLOR)")
+	rootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "do not page long output")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", client.DefaultTimeout, "per-request timeout; 0 disables (downloads: time to first byte)")
 }
 
 // versionCmd shows version information.
@@ -114,9 +305,3 @@ var versionCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
-
-// exitError prints an error and exits.
-func exitError(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
-	os.Exit(1)
-}
@@ -1,14 +1,31 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/index"
+	"github.com/rmrfslashbin/manuals-cli/internal/mirror"
 	"github.com/rmrfslashbin/manuals-cli/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var searchLimit int
+var (
+	searchLimit    int
+	searchLocal    bool
+	searchDomain   string
+	searchType     string
+	searchMinScore float64
+	searchWhere    []string
+	searchSnippets int
+	searchSnipLen  int
+	searchDocs     bool
+	searchDownload int
+	searchDir      string
+)
 
 var searchCmd = &cobra.Command{
 	Use:   "search <query>",
@@ -16,62 +33,250 @@ var searchCmd = &cobra.Command{
 	Long: `Search the Manuals database for devices matching your query.
 
 Uses semantic (vector) search to find relevant hardware and software documentation.
-Results are ranked by relevance and include snippet previews.`,
+Results are ranked by relevance and include snippet previews. Snippets of
+the top results (--snippets, default 3) are shown below the table with the
+query terms highlighted, cut to snippet_length columns around the first
+match (--snippet-length; 0 shows them in full).
+
+With --local, searches the catalog mirrored by 'manuals sync' instead,
+using a full-text index with BM25 ranking. This works without a
+connection to the server. Local scores are BM25 scores scaled to 0-1; they
+do not depend on the other results, so --min-score works as a fixed
+threshold, but they are not comparable to the server's scores.
+
+Results can be narrowed by domain, type, minimum score and device
+metadata. --where takes key=value, key!=value, key~text (contains),
+key<n, key<=n, key>n or key>=n, and may be repeated; all must match.
+Values compare numerically when both sides are numbers, and otherwise as
+text ignoring case. Filters are sent to the server and also applied to
+the results, for servers that do not support them.
+
+With --documents, searches for documents instead: the results are
+documents with their filename, MIME type and device, and the matching
+pages where the server reports them. --download-top N downloads the N
+best documents into --dir. If the server has no document search, the
+documents of the best matching devices are listed instead, those whose
+filename matches the query first.`,
 	Example: `  manuals search "raspberry pi gpio"
   manuals search "uart protocol" --limit 5
-  manuals search esp32 -o json`,
-	Args: cobra.MinimumNArgs(1),
+  manuals search "temperature" --domain hardware --type sensors --min-score 0.5
+  manuals search "wifi module" --where vendor=espressif --where "pins>=30"
+  manuals search "i2c address" --snippets 10 --snippet-length 0
+  manuals search esp32 -o json
+  manuals search --local "esp32 pinout"
+  manuals search --documents "esp32 pinout"
+  manuals search --documents "bme280 register map" --download-top 1 --dir ./datasheets`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: map[string]string{annotationNoClientFlag: "local"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := strings.Join(args, " ")
 
-		results, err := apiClient.Search(query, searchLimit)
+		filter := client.SearchFilter{
+			Domain:   searchDomain,
+			Type:     searchType,
+			MinScore: searchMinScore,
+		}
+		for _, w := range searchWhere {
+			p, err := client.ParsePredicate(w)
+			if err != nil {
+				return &usageError{err: err, cmdPath: cmd.CommandPath()}
+			}
+			filter.Where = append(filter.Where, p)
+		}
+		if searchDownload > 0 && !searchDocs {
+			return &usageError{err: errors.New("--download-top requires --documents"), cmdPath: cmd.CommandPath()}
+		}
+		if searchDocs {
+			return searchDocuments(cmd, query, filter)
+		}
+
+		var results *client.SearchResponse
+		var err error
+		if searchLocal {
+			results, err = mirror.Open(cfg.MirrorDir).Search(query, searchLimit, filter)
+		} else {
+			results, err = apiClient.SearchFilteredContext(cmd.Context(), query, searchLimit, filter)
+		}
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
-		if out.IsJSON() {
-			return out.JSON(results)
+		if out.IsStructured() {
+			return out.List(results, results.Results)
 		}
 
-		if len(results.Results) == 0 {
-			out.Println("No results found.")
-			return nil
+		if out.IsHuman() {
+			if len(results.Results) == 0 {
+				out.Println("No results found.")
+				return nil
+			}
+			out.Text("Found %d results for \"%s\":\n\n", results.Total, results.Query)
 		}
 
-		out.Text("Found %d results for \"%s\":\n\n", results.Total, results.Query)
-
 		headers := []string{"ID", "NAME", "DOMAIN", "TYPE", "SCORE"}
 		rows := make([][]string, len(results.Results))
 		for i, r := range results.Results {
 			rows[i] = []string{
-				r.DeviceID[:8],
-				output.Truncate(r.Name, 40),
+				out.ShortID(r.DeviceID),
+				r.Name,
 				r.Domain,
 				r.Type,
-				fmt.Sprintf("%.2f", r.Score),
+				out.Score(r.Score),
 			}
 		}
 		out.Table(headers, rows)
 
-		// Show snippets for top results
-		if len(results.Results) > 0 && outputFormat != "table" {
-			out.Println("\n--- Snippets ---")
+		if out.IsHuman() {
+			snippets := make([]resultSnippet, len(results.Results))
 			for i, r := range results.Results {
-				if i >= 3 {
-					break
-				}
+				snippets[i] = resultSnippet{id: r.DeviceID, title: r.Name}
 				if r.Snippet != "" {
-					out.Text("\n[%s] %s\n", r.DeviceID[:8], r.Name)
-					out.Text("  %s\n", output.Truncate(r.Snippet, 200))
+					snippets[i].texts = []string{r.Snippet}
 				}
 			}
+			showSnippets(cmd, snippets, index.Tokenize(query))
 		}
 
 		return nil
 	},
 }
 
+// searchDocuments runs a document search, optionally downloading the best
+// matches.
+func searchDocuments(cmd *cobra.Command, query string, filter client.SearchFilter) error {
+	ctx := cmd.Context()
+	results, err := apiClient.SearchDocumentsContext(ctx, query, searchLimit, filter)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if out.IsStructured() {
+		if err := out.List(results, results.Results); err != nil {
+			return err
+		}
+	} else {
+		if out.IsHuman() {
+			if len(results.Results) == 0 {
+				out.Println("No documents found.")
+				return nil
+			}
+			out.Text("Found %d documents for \"%s\"", results.Total, results.Query)
+			if results.Derived {
+				out.Text(" (from the best matching devices)")
+			}
+			out.Text(":\n\n")
+		}
+
+		headers := []string{"ID", "FILENAME", "TYPE", "PAGES", "DEVICE", "SCORE"}
+		rows := make([][]string, len(results.Results))
+		for i, r := range results.Results {
+			device := r.DeviceName
+			if device == "" {
+				device = out.ShortID(r.DeviceID)
+			}
+			rows[i] = []string{
+				out.ShortID(r.ID),
+				r.Filename,
+				r.MimeType,
+				pageList(r.Pages),
+				device,
+				out.Score(r.Score),
+			}
+		}
+		out.Table(headers, rows)
+
+		if out.IsHuman() {
+			snippets := make([]resultSnippet, len(results.Results))
+			for i, r := range results.Results {
+				snippets[i] = resultSnippet{id: r.ID, title: r.Filename}
+				for _, p := range r.Pages {
+					if p.Snippet != "" {
+						snippets[i].texts = append(snippets[i].texts, fmt.Sprintf("p. %d: %s", p.Page, p.Snippet))
+					}
+				}
+				if len(snippets[i].texts) == 0 && r.Snippet != "" {
+					snippets[i].texts = []string{r.Snippet}
+				}
+			}
+			showSnippets(cmd, snippets, index.Tokenize(query))
+		}
+	}
+
+	if searchDownload <= 0 || len(results.Results) == 0 {
+		return nil
+	}
+	docs := make([]*client.Document, 0, searchDownload)
+	for _, r := range results.Results[:min(searchDownload, len(results.Results))] {
+		docs = append(docs, &r.Document)
+	}
+	_, stats, err := downloadAll(ctx, docs, searchDir, downloadOptions{concurrency: defaultConcurrency})
+	if err != nil {
+		return err
+	}
+	if out.IsHuman() {
+		out.Text("\n%s\n", stats)
+	}
+	return stats.err(ctx)
+}
+
+// pageList formats page hits as a short list of page numbers.
+func pageList(pages []client.PageHit) string {
+	const maxPages = 5
+	nums := make([]string, 0, maxPages)
+	for _, p := range pages[:min(len(pages), maxPages)] {
+		nums = append(nums, strconv.Itoa(p.Page))
+	}
+	s := strings.Join(nums, ",")
+	if more := len(pages) - maxPages; more > 0 {
+		s += fmt.Sprintf(" +%d", more)
+	}
+	return s
+}
+
+// resultSnippet is the snippet text of a search result.
+type resultSnippet struct {
+	id, title string
+	texts     []string
+}
+
+// showSnippets prints the snippets of the first --snippets results, cut to
+// the snippet length around the first query term, with the terms
+// highlighted.
+func showSnippets(cmd *cobra.Command, results []resultSnippet, terms []string) {
+	length := cfg.SnippetLength
+	if cmd.Flags().Changed("snippet-length") {
+		length = searchSnipLen
+	}
+
+	heading := false
+	for _, r := range results[:min(searchSnippets, len(results))] {
+		if len(r.texts) == 0 {
+			continue
+		}
+		if !heading {
+			out.Println("\n--- Snippets ---")
+			heading = true
+		}
+		out.Text("\n[%s] %s\n", out.ShortID(r.id), r.title)
+		for _, text := range r.texts {
+			snippet := out.Highlight(output.Excerpt(text, terms, length), terms)
+			out.Println(out.Wrap(snippet, "  "))
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(searchCmd)
 	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 20, "maximum number of results")
+	searchCmd.Flags().BoolVar(&searchLocal, "local", false, "search the local mirror (see 'manuals sync')")
+	searchCmd.Flags().StringVarP(&searchDomain, "domain", "d", "", "filter by domain (hardware, software)")
+	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", "filter by type")
+	searchCmd.Flags().Float64Var(&searchMinScore, "min-score", 0, "minimum relevance score (0-1)")
+	searchCmd.Flags().IntVar(&searchSnippets, "snippets", 3, "number of results to show snippets for; 0 disables")
+	searchCmd.Flags().IntVar(&searchSnipLen, "snippet-length", 0, "snippet length in columns; 0 shows them in full (default from config: 200)")
+	searchCmd.Flags().StringArrayVarP(&searchWhere, "where", "w", nil, "filter by device metadata, e.g. vendor=espressif (repeatable)")
+	searchCmd.Flags().BoolVar(&searchDocs, "documents", false, "search for documents instead of devices")
+	searchCmd.Flags().IntVar(&searchDownload, "download-top", 0, "download the N best matching documents (with --documents)")
+	searchCmd.Flags().StringVar(&searchDir, "dir", ".", "directory for --download-top")
+	searchCmd.MarkFlagsMutuallyExclusive("documents", "local")
 }
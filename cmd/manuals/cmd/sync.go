@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/mirror"
+	"github.com/rmrfslashbin/manuals-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncFiles bool
+	syncQuiet bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [dir]",
+	Short: "Mirror the catalog to a local directory",
+	Long: `Mirror the device and document catalog into a local directory.
+
+Only devices and documents whose indexed time changed since the last run
+are fetched, and entries removed from the server are removed locally. Sync
+state is recorded in manifest.json in the mirror directory.
+
+The directory defaults to mirror_dir (or $XDG_DATA_HOME/manuals). Use
+--files to also download the document files, verified against their
+checksums. Files that fail to download are reported and retried on the
+next run; if all failures are checksum mismatches the exit code is 6.`,
+	Example: `  manuals sync
+  manuals sync ~/manuals-mirror --files
+  manuals sync -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := cfg.MirrorDir
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		if dir == "" {
+			return fmt.Errorf("no mirror directory: pass one or set mirror_dir")
+		}
+
+		opts := mirror.Options{Files: syncFiles}
+		if !syncQuiet {
+			opts.Progress = func(msg string) {
+				fmt.Fprintln(os.Stderr, msg)
+			}
+		}
+
+		res, err := mirror.Open(dir).Sync(cmd.Context(), apiClient, cfg.APIBaseURL, opts)
+		if err != nil {
+			return fmt.Errorf("sync failed: %w", err)
+		}
+
+		if out.IsStructured() {
+			if err := out.Value(res); err != nil {
+				return err
+			}
+			return syncFailures(res)
+		}
+
+		out.Text("Synced %s\n", dir)
+		out.Text("  Devices:   %d added, %d updated, %d removed, %d unchanged\n",
+			res.DevicesAdded, res.DevicesUpdated, res.DevicesRemoved, res.DevicesUnchanged)
+		out.Text("  Documents: %d added, %d updated, %d removed, %d unchanged\n",
+			res.DocumentsAdded, res.DocumentsUpdated, res.DocumentsRemoved, res.DocumentsUnchanged)
+		if syncFiles {
+			out.Text("  Files:     %d downloaded (%s), %d failed\n", res.FilesDownloaded, output.FormatSize(res.BytesDownloaded), len(res.Failures))
+		}
+
+		return syncFailures(res)
+	},
+}
+
+// syncFailures returns the error for the files of res that failed to
+// download, wrapping a checksum mismatch if every failure is one.
+func syncFailures(res *mirror.Result) error {
+	if len(res.Failures) == 0 {
+		return nil
+	}
+	var mismatch *checksum.MismatchError
+	for _, f := range res.Failures {
+		if !errors.As(f.Err, &mismatch) {
+			return fmt.Errorf("%d files failed to download", len(res.Failures))
+		}
+	}
+	return fmt.Errorf("%d files failed to download: %w", len(res.Failures), mismatch)
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVar(&syncFiles, "files", false, "also download document files")
+	syncCmd.Flags().BoolVarP(&syncQuiet, "quiet", "q", false, "do not report individual changes")
+}
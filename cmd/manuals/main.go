@@ -2,7 +2,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/rmrfslashbin/manuals-cli/cmd/manuals/cmd"
@@ -17,8 +16,5 @@ var (
 
 func main() {
 	cmd.SetVersionInfo(version, gitCommit, buildTime)
-	if err := cmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+	os.Exit(cmd.Execute())
 }
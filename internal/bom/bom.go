@@ -0,0 +1,245 @@
+// Package bom reads bills of materials and matches their parts to devices.
+package bom
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+)
+
+// Part is a line of a bill of materials.
+type Part struct {
+	// Line is the line number in the BOM file.
+	Line         int    `json:"line"`
+	Number       string `json:"part_number"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Query returns the search query for the part: its number and
+// manufacturer, or its description if it has no number.
+func (p Part) Query() string {
+	if p.Number == "" {
+		return strings.TrimSpace(p.Manufacturer + " " + p.Description)
+	}
+	return strings.TrimSpace(p.Number + " " + p.Manufacturer)
+}
+
+// Columns maps BOM columns to part fields. Each is a header name, matched
+// without regard to case, or a 1-based column number. Empty fields are
+// detected from common header names.
+type Columns struct {
+	Part         string
+	Manufacturer string
+	Description  string
+}
+
+// Header names recognized when a column is not given.
+var (
+	partHeaders         = []string{"part number", "part_number", "part no", "part #", "partnumber", "mpn", "mfr part number", "manufacturer part number", "pn", "part"}
+	manufacturerHeaders = []string{"manufacturer", "mfr", "mfg", "vendor", "maker", "brand"}
+	descriptionHeaders  = []string{"description", "desc", "value", "comment"}
+)
+
+// Read reads the parts of a BOM in CSV form, with fields separated by
+// comma. If header is false, the first line is a part and columns must be
+// given by number. Lines without a part number or description are skipped.
+func Read(r io.Reader, cols Columns, comma rune, header bool) ([]Part, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("BOM is empty")
+	}
+
+	var names []string
+	first := 1
+	if header {
+		names = records[0]
+		records = records[1:]
+		first = 2
+	}
+
+	part, err := column(cols.Part, names, partHeaders, true)
+	if err != nil {
+		return nil, fmt.Errorf("part number column: %w", err)
+	}
+	mfr, err := column(cols.Manufacturer, names, manufacturerHeaders, false)
+	if err != nil {
+		return nil, fmt.Errorf("manufacturer column: %w", err)
+	}
+	desc, err := column(cols.Description, names, descriptionHeaders, false)
+	if err != nil {
+		return nil, fmt.Errorf("description column: %w", err)
+	}
+
+	var parts []Part
+	for i, rec := range records {
+		p := Part{
+			Line:         first + i,
+			Number:       field(rec, part),
+			Manufacturer: field(rec, mfr),
+			Description:  field(rec, desc),
+		}
+		if p.Number == "" && p.Description == "" {
+			continue
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// column returns the 0-based index of the column named by spec, or found
+// among known header names if spec is empty; -1 if an optional column is
+// absent.
+func column(spec string, names, known []string, required bool) (int, error) {
+	if spec != "" {
+		if n, err := strconv.Atoi(spec); err == nil {
+			if n < 1 {
+				return -1, fmt.Errorf("invalid column number %d", n)
+			}
+			return n - 1, nil
+		}
+		for i, name := range names {
+			if strings.EqualFold(strings.TrimSpace(name), spec) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no column named %q (columns: %s)", spec, strings.Join(names, ", "))
+	}
+
+	for _, k := range known {
+		for i, name := range names {
+			if strings.EqualFold(strings.TrimSpace(name), k) {
+				return i, nil
+			}
+		}
+	}
+	if required {
+		if names == nil {
+			return -1, errors.New("not given (a number is needed without a header line)")
+		}
+		return -1, fmt.Errorf("not found (columns: %s)", strings.Join(names, ", "))
+	}
+	return -1, nil
+}
+
+// field returns the trimmed field i of rec, or "".
+func field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// Status is the outcome of matching a part.
+type Status string
+
+const (
+	// Matched parts have a single best device.
+	Matched Status = "matched"
+
+	// Ambiguous parts have several devices scoring about as well.
+	Ambiguous Status = "ambiguous"
+
+	// Unmatched parts have no device scoring above the threshold.
+	Unmatched Status = "unmatched"
+)
+
+// MatchOptions controls how search results are matched to a part.
+type MatchOptions struct {
+	// MinScore is the score a device needs to match.
+	MinScore float64
+
+	// Margin is how far ahead of the runner-up the best device must
+	// score; closer results make the part ambiguous.
+	Margin float64
+}
+
+// minNameMatch is the shortest part number matched against device names;
+// shorter ones (such as "R1") would match by accident.
+const minNameMatch = 4
+
+// Match picks the device for p from its search results, ranked best first.
+// A device whose name contains the part number matches outright, unless
+// several do; any other best result must score at least MinScore and lead
+// the next by more than Margin. It returns the status, the matched device,
+// and the candidates to review for ambiguous parts.
+func Match(p Part, results []client.SearchResult, opts MatchOptions) (Status, *client.SearchResult, []client.SearchResult) {
+	if key := normalize(p.Number); len(key) >= minNameMatch {
+		var named []client.SearchResult
+		for _, r := range results {
+			if strings.Contains(normalize(r.Name), key) {
+				named = append(named, r)
+			}
+		}
+		switch len(named) {
+		case 0:
+		case 1:
+			return Matched, &named[0], nil
+		default:
+			return Ambiguous, nil, named
+		}
+	}
+
+	var above []client.SearchResult
+	for _, r := range results {
+		if r.Score >= opts.MinScore {
+			above = append(above, r)
+		}
+	}
+	switch {
+	case len(above) == 0:
+		return Unmatched, nil, nil
+	case len(above) == 1 || above[0].Score-above[1].Score > opts.Margin:
+		return Matched, &above[0], nil
+	}
+
+	var near []client.SearchResult
+	for _, r := range above {
+		if above[0].Score-r.Score <= opts.Margin {
+			near = append(near, r)
+		}
+	}
+	return Ambiguous, nil, near
+}
+
+// normalize lowercases s and drops everything but letters and digits, so
+// part numbers compare regardless of punctuation.
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Folder returns a directory name for the part: its number with characters
+// that are unsafe in file names replaced, or "line-N" if it has none.
+func (p Part) Folder() string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, p.Number)
+	name = strings.Trim(name, "._")
+	if name == "" {
+		return fmt.Sprintf("line-%d", p.Line)
+	}
+	return name
+}
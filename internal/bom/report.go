@@ -0,0 +1,104 @@
+package bom
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReportName is the file name of the index report written by WriteReport.
+const ReportName = "index.md"
+
+// Result is the outcome of importing a part.
+type Result struct {
+	Part
+
+	Status Status `json:"status"`
+
+	// DeviceID, DeviceName and Score describe the matched device.
+	DeviceID   string  `json:"device_id,omitempty"`
+	DeviceName string  `json:"device_name,omitempty"`
+	Score      float64 `json:"score,omitempty"`
+
+	// Candidates lists the devices to choose from for ambiguous parts, as
+	// "<id>  <name> (<score>)".
+	Candidates []string `json:"candidates,omitempty"`
+
+	// Documents are the downloaded files, relative to the output directory.
+	Documents []string `json:"documents,omitempty"`
+
+	// Error describes a failed search or download.
+	Error string `json:"error,omitempty"`
+}
+
+// NeedsReview reports whether the part must be checked by hand.
+func (r Result) NeedsReview() bool {
+	return r.Status != Matched || r.Error != ""
+}
+
+// WriteReport writes a Markdown index of the import: a table of every part
+// with its device and documents, linked relative to the output directory,
+// followed by the parts that need review.
+func WriteReport(w io.Writer, source string, results []Result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# BOM documentation: %s\n\n", filepath.Base(source))
+	fmt.Fprintf(&b, "Generated by `manuals bom import` on %s.\n\n", time.Now().Format("2006-01-02 15:04"))
+
+	counts := map[Status]int{}
+	review := 0
+	for _, r := range results {
+		counts[r.Status]++
+		if r.NeedsReview() {
+			review++
+		}
+	}
+	fmt.Fprintf(&b, "%d parts: %d matched, %d ambiguous, %d unmatched; %d need review.\n\n",
+		len(results), counts[Matched], counts[Ambiguous], counts[Unmatched], review)
+
+	b.WriteString("| Line | Part | Manufacturer | Status | Device | Score | Documents |\n")
+	b.WriteString("| ---: | --- | --- | --- | --- | ---: | --- |\n")
+	for _, r := range results {
+		var docs []string
+		for _, d := range r.Documents {
+			docs = append(docs, fmt.Sprintf("[%s](%s)", escapeCell(filepath.Base(d)), filepath.ToSlash(d)))
+		}
+		score := ""
+		if r.Status == Matched {
+			score = fmt.Sprintf("%.2f", r.Score)
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s | %s |\n",
+			r.Line, escapeCell(r.Number), escapeCell(r.Manufacturer), r.Status,
+			escapeCell(r.DeviceName), score, strings.Join(docs, "<br>"))
+	}
+
+	if review > 0 {
+		b.WriteString("\n## Needs review\n\n")
+		for _, r := range results {
+			if !r.NeedsReview() {
+				continue
+			}
+			name := r.Number
+			if name == "" {
+				name = r.Description
+			}
+			fmt.Fprintf(&b, "- **Line %d, %s**: %s", r.Line, name, r.Status)
+			if r.Error != "" {
+				fmt.Fprintf(&b, " (%s)", r.Error)
+			}
+			b.WriteString("\n")
+			for _, c := range r.Candidates {
+				fmt.Fprintf(&b, "  - `%s`\n", c)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeCell escapes text for a Markdown table cell.
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,220 @@
+// Package cache implements an on-disk cache of API responses.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry is a cached API response.
+type Entry struct {
+	// Key is the request the response belongs to.
+	Key string `json:"key"`
+
+	// ETag is the server's entity tag, used for revalidation.
+	ETag string `json:"etag,omitempty"`
+
+	// StoredAt is when the response was fetched or last revalidated.
+	StoredAt time.Time `json:"stored_at"`
+
+	// Body is the raw response body.
+	Body json.RawMessage `json:"body"`
+}
+
+// Fresh reports whether the entry is younger than ttl.
+func (e *Entry) Fresh(ttl time.Duration) bool {
+	return time.Since(e.StoredAt) < ttl
+}
+
+// Cache stores API responses as files in a directory, one per key.
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// New creates a cache in dir whose entries are fresh for ttl.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// TTL returns how long entries are considered fresh.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get returns the entry for key, if one exists, regardless of its age.
+func (c *Cache) Get(key string) (*Entry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return nil, false
+	}
+	return &e, true
+}
+
+// Put stores a response body for key.
+func (c *Cache) Put(key, etag string, body []byte) error {
+	if !json.Valid(body) {
+		return errors.New("cache: response body is not valid JSON")
+	}
+	return c.write(&Entry{
+		Key:      key,
+		ETag:     etag,
+		StoredAt: time.Now(),
+		Body:     body,
+	})
+}
+
+// Touch marks the entry for key as fresh again, e.g. after the server
+// confirmed it is unchanged.
+func (c *Cache) Touch(key string) error {
+	e, ok := c.Get(key)
+	if !ok {
+		return fs.ErrNotExist
+	}
+	e.StoredAt = time.Now()
+	return c.write(e)
+}
+
+// Stats describes the contents of the cache.
+type Stats struct {
+	Dir     string        `json:"dir"`
+	TTL     time.Duration `json:"ttl"`
+	Entries int           `json:"entries"`
+	Expired int           `json:"expired"`
+	Bytes   int64         `json:"bytes"`
+}
+
+// Stats returns statistics about the cache.
+func (c *Cache) Stats() (*Stats, error) {
+	stats := &Stats{Dir: c.dir, TTL: c.ttl}
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if e, err := c.read(path); err != nil || !e.Fresh(c.ttl) {
+			stats.Expired++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Clear removes every entry and returns how many were removed.
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, _ fs.FileInfo) error {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Prune removes expired and unreadable entries and returns how many were
+// removed.
+func (c *Cache) Prune() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, _ fs.FileInfo) error {
+		if e, err := c.read(path); err == nil && e.Fresh(c.ttl) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// path returns the file holding the entry for key.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// read loads the entry stored in path.
+func (c *Cache) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// write stores an entry atomically.
+func (c *Cache) write(e *Entry) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(e.Key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// walk calls fn for every entry file in the cache directory.
+func (c *Cache) walk(fn func(path string, info fs.FileInfo) error) error {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+
+	for _, de := range entries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		if err := fn(filepath.Join(c.dir, de.Name()), info); err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+	}
+	return nil
+}
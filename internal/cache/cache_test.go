@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPutGet(t *testing.T) {
+	c := New(t.TempDir(), time.Hour)
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("Get of an empty cache found an entry")
+	}
+	if err := c.Put("k", `"v1"`, []byte(`{"id":"a"}`)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	e, ok := c.Get("k")
+	if !ok {
+		t.Fatal("Get found no entry after Put")
+	}
+	if e.Key != "k" || e.ETag != `"v1"` || string(e.Body) != `{"id":"a"}` || !e.Fresh(time.Hour) {
+		t.Errorf("entry = %+v", e)
+	}
+	if _, ok := c.Get("other"); ok {
+		t.Error("Get of another key found an entry")
+	}
+
+	if err := c.Put("k", "", []byte("not json")); err == nil {
+		t.Error("Put of an invalid body succeeded")
+	}
+	if e, _ := c.Get("k"); string(e.Body) != `{"id":"a"}` {
+		t.Errorf("a failed Put replaced the entry: %s", e.Body)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	c := New(t.TempDir(), time.Minute)
+	if err := c.Touch("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Touch of a missing entry: %v, want ErrNotExist", err)
+	}
+
+	if err := c.Put("k", `"v1"`, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := c.Get("k")
+	e.StoredAt = time.Now().Add(-time.Hour)
+	if err := c.write(e); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := c.Get("k"); e.Fresh(c.TTL()) {
+		t.Fatal("backdated entry is fresh")
+	}
+
+	if err := c.Touch("k"); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	e, _ = c.Get("k")
+	if !e.Fresh(c.TTL()) || e.ETag != `"v1"` {
+		t.Errorf("after Touch: %+v, want a fresh entry with its ETag", e)
+	}
+}
+
+func TestStatsPruneClear(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Put(key, "", []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, _ := c.Get("a")
+	e.StoredAt = time.Now().Add(-time.Hour)
+	if err := c.write(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "junk.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Files that are not entries are left alone.
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 4 || stats.Expired != 2 || stats.Bytes == 0 {
+		t.Errorf("Stats = %+v, want 4 entries, 2 expired", stats)
+	}
+
+	if n, err := c.Prune(); err != nil || n != 2 {
+		t.Errorf("Prune = %d, %v; want 2", n, err)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("expired entry survived Prune")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("fresh entry was pruned")
+	}
+
+	if n, err := c.Clear(); err != nil || n != 2 {
+		t.Errorf("Clear = %d, %v; want 2", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Errorf("Clear removed a file that is not an entry: %v", err)
+	}
+
+	if n, err := New(filepath.Join(dir, "missing"), time.Minute).Clear(); err != nil || n != 0 {
+		t.Errorf("Clear of a missing directory = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package checksum verifies document contents against their checksums.
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUnsupported is returned when the checksum algorithm cannot be detected.
+var ErrUnsupported = errors.New("unsupported checksum format")
+
+// MismatchError is returned when content does not match its checksum.
+type MismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+// Error implements the error interface.
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch (%s): expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// Verifier hashes content written to it and compares the result with an
+// expected checksum.
+type Verifier struct {
+	hash.Hash
+	algorithm string
+	expected  string
+}
+
+// New returns a Verifier for checksum. The algorithm is taken from an
+// "algo:" prefix (md5, sha1, sha256, sha512) if present, and otherwise
+// detected from the length of the hex digest.
+func New(checksum string) (*Verifier, error) {
+	algo, digest, found := strings.Cut(strings.TrimSpace(checksum), ":")
+	if !found {
+		digest = algo
+		switch len(digest) {
+		case 32:
+			algo = "md5"
+		case 40:
+			algo = "sha1"
+		case 64:
+			algo = "sha256"
+		case 128:
+			algo = "sha512"
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnsupported, checksum)
+		}
+	}
+	algo = strings.ReplaceAll(strings.ToLower(algo), "-", "")
+	digest = strings.ToLower(digest)
+
+	if _, err := hex.DecodeString(digest); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, checksum)
+	}
+
+	var h hash.Hash
+	switch algo {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, checksum)
+	}
+	if hex.EncodedLen(h.Size()) != len(digest) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, checksum)
+	}
+
+	return &Verifier{Hash: h, algorithm: algo, expected: digest}, nil
+}
+
+// Algorithm returns the detected hash algorithm.
+func (v *Verifier) Algorithm() string {
+	return v.algorithm
+}
+
+// Verify compares the content written so far with the expected checksum,
+// returning a *MismatchError if they differ.
+func (v *Verifier) Verify() error {
+	actual := hex.EncodeToString(v.Sum(nil))
+	if actual != v.expected {
+		return &MismatchError{Algorithm: v.algorithm, Expected: v.expected, Actual: actual}
+	}
+	return nil
+}
+
+// File verifies the file at path against checksum.
+func File(path, checksum string) error {
+	v, err := New(checksum)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(v, f); err != nil {
+		return err
+	}
+	return v.Verify()
+}
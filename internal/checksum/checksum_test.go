@@ -0,0 +1,106 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Digests of "hello".
+const (
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+	helloSHA1   = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	helloSHA512 = "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		checksum, algorithm string
+	}{
+		// Detected from the digest length.
+		{helloMD5, "md5"},
+		{helloSHA1, "sha1"},
+		{helloSHA256, "sha256"},
+		{helloSHA512, "sha512"},
+		{strings.ToUpper(helloSHA256), "sha256"},
+		{"  " + helloSHA256 + "\n", "sha256"},
+
+		// Named by a prefix, in any case and with or without a dash.
+		{"md5:" + helloMD5, "md5"},
+		{"sha1:" + helloSHA1, "sha1"},
+		{"sha256:" + helloSHA256, "sha256"},
+		{"SHA-256:" + helloSHA256, "sha256"},
+		{"sha512:" + helloSHA512, "sha512"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.checksum, func(t *testing.T) {
+			v, err := New(tt.checksum)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if v.Algorithm() != tt.algorithm {
+				t.Errorf("algorithm %s, want %s", v.Algorithm(), tt.algorithm)
+			}
+			v.Write([]byte("hello"))
+			if err := v.Verify(); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewUnsupported(t *testing.T) {
+	tests := []string{
+		"",
+		"abc123",
+		helloSHA256[:63],
+		helloSHA256 + "0",
+		strings.Repeat("z", 64),
+		"crc32:" + helloMD5,
+		"sha256:" + helloMD5,        // digest length does not match the prefix
+		"md5:" + helloSHA256,        // nor here
+		"sha256:" + helloSHA256[2:], // truncated
+	}
+	for _, checksum := range tests {
+		if _, err := New(checksum); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("New(%q) error = %v, want ErrUnsupported", checksum, err)
+		}
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	v, err := New("sha256:" + helloSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Write([]byte("hello!"))
+
+	var mismatch *MismatchError
+	if err := v.Verify(); !errors.As(err, &mismatch) {
+		t.Fatalf("Verify error = %v, want *MismatchError", err)
+	}
+	if mismatch.Algorithm != "sha256" || mismatch.Expected != helloSHA256 || mismatch.Actual == helloSHA256 {
+		t.Errorf("got %+v", mismatch)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := File(path, helloSHA1); err != nil {
+		t.Errorf("File: %v", err)
+	}
+	var mismatch *MismatchError
+	if err := File(path, helloSHA256[:62]+"00"); !errors.As(err, &mismatch) {
+		t.Errorf("File with a wrong checksum: %v, want *MismatchError", err)
+	}
+	if err := File(filepath.Join(t.TempDir(), "missing"), helloSHA1); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("File of a missing file: %v, want not exist", err)
+	}
+}
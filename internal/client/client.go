@@ -2,35 +2,131 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/cache"
 )
 
 const (
 	// APIVersion is the API version to use.
 	APIVersion = "2025.12"
+
+	// DefaultTimeout is the default per-call deadline.
+	DefaultTimeout = 30 * time.Second
 )
 
+// ErrOffline is returned in offline mode when a response is not cached.
+var ErrOffline = errors.New("not available offline")
+
+// errRequestBuild marks failures to construct a request, which are never retried.
+var errRequestBuild = errors.New("failed to create request")
+
+// errNoResponse is the cause of cancelling a download attempt whose
+// response headers did not arrive within the client timeout.
+var errNoResponse = errors.New("no response")
+
 // Client is an HTTP client for the Manuals API.
 type Client struct {
 	baseURL    string
 	apiKey     string
+	timeout    time.Duration
+	retry      RetryPolicy
+	debug      io.Writer
+	cache      *cache.Cache
+	offline    bool
+	revalidate bool
 	httpClient *http.Client
 }
 
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTimeout sets the per-call deadline. For JSON endpoints it bounds the
+// whole request; for downloads it bounds only the wait for response headers,
+// so long transfers are limited solely by the caller's context. A zero or
+// negative value disables the deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetry sets the retry policy for idempotent requests.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithDebug enables debug output (requests and retries) to w.
+func WithDebug(w io.Writer) Option {
+	return func(c *Client) {
+		c.debug = w
+	}
+}
+
+// WithCache enables caching of JSON responses in c.
+func WithCache(c *cache.Cache) Option {
+	return func(cl *Client) {
+		cl.cache = c
+	}
+}
+
+// WithOffline makes the client serve responses only from its cache,
+// regardless of their age, and never contact the server.
+func WithOffline(offline bool) Option {
+	return func(c *Client) {
+		c.offline = offline
+	}
+}
+
+// WithRevalidate makes the client check cached responses with the server
+// on every request, even while they are fresh, so it never returns data
+// older than the server's. Cached responses still save transfers when
+// the server answers 304 Not Modified. Offline mode takes precedence.
+func WithRevalidate(revalidate bool) Option {
+	return func(c *Client) {
+		c.revalidate = revalidate
+	}
+}
+
+// WithHTTPClient sets the underlying HTTP client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
 // New creates a new API client.
-func New(baseURL, apiKey string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+func New(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		timeout:    DefaultTimeout,
+		retry:      DefaultRetryPolicy,
+		httpClient: &http.Client{},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// With returns a copy of c with opts applied.
+func (c *Client) With(opts ...Option) *Client {
+	cp := *c
+	for _, opt := range opts {
+		opt(&cp)
+	}
+	return &cp
 }
 
 // SearchResult represents a search result.
@@ -42,6 +138,9 @@ type SearchResult struct {
 	Path     string  `json:"path"`
 	Score    float64 `json:"score"`
 	Snippet  string  `json:"snippet"`
+
+	// Metadata is the device metadata, if the server includes it.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // SearchResponse is the response from the search endpoint.
@@ -93,11 +192,18 @@ type DocumentsResponse struct {
 
 // ErrorResponse is an API error response.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Search searches for devices.
 func (c *Client) Search(query string, limit int) (*SearchResponse, error) {
+	return c.SearchContext(context.Background(), query, limit)
+}
+
+// SearchContext searches for devices using the provided context.
+func (c *Client) SearchContext(ctx context.Context, query string, limit int) (*SearchResponse, error) {
 	params := url.Values{}
 	params.Set("q", query)
 	if limit > 0 {
@@ -105,7 +211,7 @@ func (c *Client) Search(query string, limit int) (*SearchResponse, error) {
 	}
 
 	var resp SearchResponse
-	if err := c.get("/search?"+params.Encode(), &resp); err != nil {
+	if err := c.get(ctx, "/search?"+params.Encode(), &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -113,6 +219,11 @@ func (c *Client) Search(query string, limit int) (*SearchResponse, error) {
 
 // ListDevices lists devices with pagination.
 func (c *Client) ListDevices(limit, offset int, domain, deviceType string) (*DevicesResponse, error) {
+	return c.ListDevicesContext(context.Background(), limit, offset, domain, deviceType)
+}
+
+// ListDevicesContext lists devices with pagination using the provided context.
+func (c *Client) ListDevicesContext(ctx context.Context, limit, offset int, domain, deviceType string) (*DevicesResponse, error) {
 	params := url.Values{}
 	if limit > 0 {
 		params.Set("limit", fmt.Sprintf("%d", limit))
@@ -133,7 +244,7 @@ func (c *Client) ListDevices(limit, offset int, domain, deviceType string) (*Dev
 	}
 
 	var resp DevicesResponse
-	if err := c.get(path, &resp); err != nil {
+	if err := c.get(ctx, path, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -141,8 +252,13 @@ func (c *Client) ListDevices(limit, offset int, domain, deviceType string) (*Dev
 
 // GetDevice gets a device by ID.
 func (c *Client) GetDevice(id string) (*Device, error) {
+	return c.GetDeviceContext(context.Background(), id)
+}
+
+// GetDeviceContext gets a device by ID using the provided context.
+func (c *Client) GetDeviceContext(ctx context.Context, id string) (*Device, error) {
 	var resp Device
-	if err := c.get("/devices/"+id, &resp); err != nil {
+	if err := c.get(ctx, "/devices/"+id, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -150,6 +266,11 @@ func (c *Client) GetDevice(id string) (*Device, error) {
 
 // ListDocuments lists documents with pagination.
 func (c *Client) ListDocuments(limit, offset int, deviceID string) (*DocumentsResponse, error) {
+	return c.ListDocumentsContext(context.Background(), limit, offset, deviceID)
+}
+
+// ListDocumentsContext lists documents with pagination using the provided context.
+func (c *Client) ListDocumentsContext(ctx context.Context, limit, offset int, deviceID string) (*DocumentsResponse, error) {
 	params := url.Values{}
 	if limit > 0 {
 		params.Set("limit", fmt.Sprintf("%d", limit))
@@ -167,7 +288,7 @@ func (c *Client) ListDocuments(limit, offset int, deviceID string) (*DocumentsRe
 	}
 
 	var resp DocumentsResponse
-	if err := c.get(path, &resp); err != nil {
+	if err := c.get(ctx, path, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -175,8 +296,13 @@ func (c *Client) ListDocuments(limit, offset int, deviceID string) (*DocumentsRe
 
 // GetDocument gets a document by ID.
 func (c *Client) GetDocument(id string) (*Document, error) {
+	return c.GetDocumentContext(context.Background(), id)
+}
+
+// GetDocumentContext gets a document by ID using the provided context.
+func (c *Client) GetDocumentContext(ctx context.Context, id string) (*Document, error) {
 	var resp Document
-	if err := c.get("/documents/"+id, &resp); err != nil {
+	if err := c.get(ctx, "/documents/"+id, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -184,62 +310,217 @@ func (c *Client) GetDocument(id string) (*Document, error) {
 
 // DownloadDocument downloads a document and returns the content.
 func (c *Client) DownloadDocument(id string) (io.ReadCloser, string, error) {
-	req, err := http.NewRequest("GET", c.baseURL+"/api/"+APIVersion+"/documents/"+id+"/download", nil)
+	return c.DownloadDocumentContext(context.Background(), id)
+}
+
+// DownloadDocumentContext downloads a document using the provided context.
+// The returned body remains tied to ctx; closing it releases the request.
+func (c *Client) DownloadDocumentContext(ctx context.Context, id string) (io.ReadCloser, string, error) {
+	dl, err := c.DownloadRange(ctx, id, 0, "")
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, "", err
 	}
-	req.Header.Set("X-API-Key", c.apiKey)
+	return dl.Body, dl.Filename, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// get performs a GET request and decodes the JSON response. When a cache is
+// configured, fresh entries are served without a request, stale entries (and
+// all entries with WithRevalidate) are revalidated with If-None-Match, and in
+// offline mode only the cache is consulted.
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	var key string
+	var entry *cache.Entry
+	header := http.Header{}
+	if c.cache != nil {
+		key = c.baseURL + "/api/" + APIVersion + path
+		if e, ok := c.cache.Get(key); ok {
+			if c.offline || (!c.revalidate && e.Fresh(c.cache.TTL())) {
+				c.debugf("cache hit: GET %s", path)
+				return decodeBody(e.Body, result)
+			}
+			entry = e
+			if e.ETag != "" {
+				header.Set("If-None-Match", e.ETag)
+			}
+		}
+	}
+	if c.offline {
+		return fmt.Errorf("GET %s: %w", path, ErrOffline)
+	}
+
+	resp, err := c.send(ctx, path, header, false)
 	if err != nil {
-		return nil, "", fmt.Errorf("request failed: %w", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		c.debugf("cache revalidated: GET %s", path)
+		_ = c.cache.Touch(key)
+		return decodeBody(entry.Body, result)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		return nil, "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
-	}
-
-	// Get filename from Content-Disposition header
-	filename := ""
-	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
-		// Simple parsing - look for filename=
-		if idx := len("attachment; filename="); len(cd) > idx {
-			filename = cd[idx:]
-			filename = filename[1 : len(filename)-1] // Remove quotes
+		return newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := decodeBody(body, result); err != nil {
+		return err
+	}
+
+	if c.cache != nil {
+		if err := c.cache.Put(key, resp.Header.Get("ETag"), body); err != nil {
+			c.debugf("%v", err)
 		}
 	}
 
-	return resp.Body, filename, nil
+	return nil
 }
 
-// get performs a GET request and decodes the JSON response.
-func (c *Client) get(path string, result interface{}) error {
-	req, err := http.NewRequest("GET", c.baseURL+"/api/"+APIVersion+path, nil)
+// decodeBody decodes a JSON response body.
+func decodeBody(body []byte, result interface{}) error {
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send performs a GET request against the API with optional extra headers,
+// retrying transient failures according to the client's retry policy.
+// When headersOnly is set the client timeout bounds only the wait for
+// response headers; otherwise it bounds the whole attempt, including
+// reading the body. The caller must close the returned body, which also
+// releases the attempt's context.
+func (c *Client) send(ctx context.Context, path string, header http.Header, headersOnly bool) (*http.Response, error) {
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, path, header, headersOnly)
+
+		if attempt >= attempts || ctx.Err() != nil || !retryable(resp, err) {
+			if errors.Is(err, errRequestBuild) {
+				return nil, err
+			}
+			if err != nil {
+				return nil, &NetworkError{Err: err}
+			}
+			return resp, nil
+		}
+
+		wait := c.retry.backoff(attempt)
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+				if c.retry.MaxDelay > 0 {
+					wait = min(wait, c.retry.MaxDelay)
+				}
+			}
+			// Drain so the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		c.debugf("GET %s: %s; retrying in %s (attempt %d/%d)", path, reason, wait.Round(time.Millisecond), attempt+1, attempts)
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, &NetworkError{Err: ctx.Err()}
+		case <-t.C:
+		}
+	}
+}
+
+// attempt performs a single GET request.
+func (c *Client) attempt(ctx context.Context, path string, header http.Header, headersOnly bool) (*http.Response, error) {
+	var cancel context.CancelFunc
+	var cancelCause context.CancelCauseFunc
+	if c.timeout > 0 && !headersOnly {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	} else {
+		ctx, cancelCause = context.WithCancelCause(ctx)
+		cancel = func() { cancelCause(nil) }
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/"+APIVersion+path, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		cancel()
+		return nil, fmt.Errorf("%w: %v", errRequestBuild, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
 	}
 	req.Header.Set("X-API-Key", c.apiKey)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+	c.debugf("GET %s", req.URL.Redacted())
+
+	// For downloads the timeout only covers the wait for response headers;
+	// once the transfer starts it is bounded by the caller's context alone.
+	// The timer cancels the attempt only while no response has arrived.
+	var (
+		mu       sync.Mutex
+		answered bool
+	)
+	if c.timeout > 0 && headersOnly {
+		timer := time.AfterFunc(c.timeout, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if !answered {
+				cancelCause(errNoResponse)
+			}
+		})
+		defer timer.Stop()
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+	resp, err := c.httpClient.Do(req)
+	mu.Lock()
+	answered = true
+	mu.Unlock()
+	if errors.Is(context.Cause(ctx), errNoResponse) {
+		if err == nil {
+			resp.Body.Close()
 		}
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, errResp.Error)
+		cancel()
+		return nil, fmt.Errorf("no response within %s", c.timeout)
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	return nil
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the request context when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// debugf writes a debug message if debug output is enabled.
+func (c *Client) debugf(format string, args ...interface{}) {
+	if c.debug == nil {
+		return
+	}
+	fmt.Fprintf(c.debug, "debug: "+format+"\n", args...)
 }
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/cache"
+)
+
+// etagServer serves a device whose name and ETag can be changed, answering
+// If-None-Match with 304 Not Modified, and records the If-None-Match
+// headers it receives.
+type etagServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	name, etag  string
+	ifNoneMatch []string
+}
+
+func newETagServer(t *testing.T) *etagServer {
+	t.Helper()
+	s := &etagServer{name: "ESP32", etag: `"v1"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ifNoneMatch = append(s.ifNoneMatch, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", s.etag)
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"id":"dev1","name":"` + s.name + `"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// change gives the device a new name and ETag.
+func (s *etagServer) change(name, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name, s.etag = name, etag
+}
+
+// requests returns the If-None-Match header of each request so far.
+func (s *etagServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ifNoneMatch...)
+}
+
+func TestGetCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		revalidate  bool
+		change      bool // the device changes after the first request
+		wantName    string
+		wantRequest []string // If-None-Match of each request
+	}{
+		{"fresh entry", time.Hour, false, false, "ESP32", []string{""}},
+		{"stale entry unchanged", 0, false, false, "ESP32", []string{"", `"v1"`}},
+		{"stale entry changed", 0, false, true, "ESP32-S3", []string{"", `"v1"`}},
+		{"fresh entry revalidated", time.Hour, true, true, "ESP32-S3", []string{"", `"v1"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newETagServer(t)
+			cc := cache.New(t.TempDir(), tt.ttl)
+			c := New(srv.URL, "key", WithCache(cc), WithRevalidate(tt.revalidate))
+
+			if d, err := c.GetDeviceContext(context.Background(), "dev1"); err != nil || d.Name != "ESP32" {
+				t.Fatalf("first GetDevice = %+v, %v", d, err)
+			}
+			if tt.change {
+				srv.change("ESP32-S3", `"v2"`)
+			}
+			d, err := c.GetDeviceContext(context.Background(), "dev1")
+			if err != nil {
+				t.Fatalf("second GetDevice: %v", err)
+			}
+			if d.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", d.Name, tt.wantName)
+			}
+
+			got := srv.requests()
+			if len(got) != len(tt.wantRequest) {
+				t.Fatalf("If-None-Match headers %q, want %q", got, tt.wantRequest)
+			}
+			for i := range got {
+				if got[i] != tt.wantRequest[i] {
+					t.Errorf("request %d If-None-Match = %q, want %q", i, got[i], tt.wantRequest[i])
+				}
+			}
+
+			// The cache holds the latest response.
+			e, ok := cc.Get(srv.URL + "/api/" + APIVersion + "/devices/dev1")
+			if !ok {
+				t.Fatal("response not cached")
+			}
+			if wantETag := map[bool]string{false: `"v1"`, true: `"v2"`}[tt.change]; e.ETag != wantETag {
+				t.Errorf("cached ETag = %s, want %s", e.ETag, wantETag)
+			}
+		})
+	}
+}
+
+func TestGetOffline(t *testing.T) {
+	srv := newETagServer(t)
+	cc := cache.New(t.TempDir(), 0)
+	if _, err := New(srv.URL, "key", WithCache(cc)).GetDeviceContext(context.Background(), "dev1"); err != nil {
+		t.Fatal(err)
+	}
+	srv.change("ESP32-S3", `"v2"`)
+
+	// Offline, stale entries are served and nothing else is fetched.
+	c := New(srv.URL, "key", WithCache(cc), WithOffline(true))
+	if d, err := c.GetDeviceContext(context.Background(), "dev1"); err != nil || d.Name != "ESP32" {
+		t.Errorf("offline GetDevice = %+v, %v; want the cached device", d, err)
+	}
+	if _, err := c.GetDeviceContext(context.Background(), "dev2"); !errors.Is(err, ErrOffline) {
+		t.Errorf("offline GetDevice of an uncached device: %v, want ErrOffline", err)
+	}
+	if n := len(srv.requests()); n != 1 {
+		t.Errorf("made %d requests, want only the first", n)
+	}
+}
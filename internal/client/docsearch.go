@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DocumentSearchResult is a document matching a search.
+type DocumentSearchResult struct {
+	Document
+
+	// DeviceName is the name of the document's device, if known.
+	DeviceName string `json:"device_name,omitempty"`
+
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+
+	// Pages lists the best matching pages, if the server indexes pages.
+	Pages []PageHit `json:"pages,omitempty"`
+}
+
+// PageHit is a matching page of a document.
+type PageHit struct {
+	Page    int     `json:"page"`
+	Score   float64 `json:"score,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+// DocumentSearchResponse is the response from the document search
+// endpoint.
+type DocumentSearchResponse struct {
+	Results []DocumentSearchResult `json:"results"`
+	Total   int                    `json:"total"`
+	Query   string                 `json:"query"`
+
+	// Derived is set when the server has no document search and the
+	// results were derived from a device search. Such results carry the
+	// device's score and snippet, and no page hits.
+	Derived bool `json:"derived,omitempty"`
+}
+
+// SearchDocumentsContext searches for documents matching query and filter.
+// Filters on domain, type and metadata apply to each document's device.
+//
+// Servers without a document search endpoint are handled by searching
+// devices and ranking their documents: documents of better matching
+// devices come first, and among a device's documents those whose filename
+// or path contains query terms.
+func (c *Client) SearchDocumentsContext(ctx context.Context, query string, limit int, filter SearchFilter) (*DocumentSearchResponse, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	filter.params(params)
+
+	var resp DocumentSearchResponse
+	err := c.get(ctx, "/search/documents?"+params.Encode(), &resp)
+	if isUnsupported(err) {
+		return c.deriveDocumentSearch(ctx, query, limit, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// As with device search, servers may ignore the filters.
+	if !filter.IsZero() {
+		results, rejected, err := c.filterDocuments(ctx, resp.Results, limit, filter)
+		if err != nil {
+			return nil, err
+		}
+		if rejected > 0 {
+			resp.Total = max(resp.Total-rejected, len(results))
+		}
+		resp.Results = results
+	}
+	return &resp, nil
+}
+
+// isUnsupported reports whether err means the server lacks an endpoint.
+func isUnsupported(err error) bool {
+	return IsNotFound(err) || hasStatus(err, http.StatusNotImplemented)
+}
+
+// filterDocuments applies filter to document results, looking up each
+// document's device when the filter needs its domain, type or metadata.
+func (c *Client) filterDocuments(ctx context.Context, results []DocumentSearchResult, limit int, filter SearchFilter) ([]DocumentSearchResult, int, error) {
+	devices := map[string]*Device{}
+	matched := make([]DocumentSearchResult, 0, len(results))
+	rejected := 0
+	for _, r := range results {
+		if limit > 0 && len(matched) >= limit {
+			break
+		}
+		sr := SearchResult{DeviceID: r.DeviceID, Name: r.DeviceName, Score: r.Score}
+		if filter.Domain != "" || filter.Type != "" || len(filter.Where) > 0 {
+			d, ok := devices[r.DeviceID]
+			if !ok {
+				var err error
+				if d, err = c.GetDeviceContext(ctx, r.DeviceID); err != nil {
+					return nil, 0, fmt.Errorf("device %s: %w", r.DeviceID, err)
+				}
+				devices[r.DeviceID] = d
+			}
+			sr.Domain, sr.Type, sr.Metadata = d.Domain, d.Type, d.Metadata
+			if r.DeviceName == "" {
+				r.DeviceName = d.Name
+			}
+		}
+		if ok, _, _ := filter.apply([]SearchResult{sr}, 0, nil); len(ok) == 0 {
+			rejected++
+			continue
+		}
+		matched = append(matched, r)
+	}
+	return matched, rejected, nil
+}
+
+// deriveDocumentSearch answers a document search from a device search.
+func (c *Client) deriveDocumentSearch(ctx context.Context, query string, limit int, filter SearchFilter) (*DocumentSearchResponse, error) {
+	c.debugf("no document search endpoint; deriving results from device search")
+
+	devices, err := c.SearchFilteredContext(ctx, query, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := queryTerms(query)
+	resp := &DocumentSearchResponse{
+		Results: []DocumentSearchResult{},
+		Query:   query,
+		Derived: true,
+	}
+	for _, d := range devices.Results {
+		for doc, err := range c.AllDocuments(ctx, 0, d.DeviceID) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to list documents of %s: %w", d.Name, err)
+			}
+			resp.Results = append(resp.Results, DocumentSearchResult{
+				Document:   doc,
+				DeviceName: d.Name,
+				Score:      d.Score * (0.75 + 0.25*termCoverage(doc.Filename+" "+doc.Path, terms)),
+				Snippet:    d.Snippet,
+			})
+		}
+	}
+
+	sort.SliceStable(resp.Results, func(i, j int) bool {
+		return resp.Results[i].Score > resp.Results[j].Score
+	})
+	resp.Total = len(resp.Results)
+	if limit > 0 && len(resp.Results) > limit {
+		resp.Results = resp.Results[:limit]
+	}
+	return resp, nil
+}
+
+// queryTerms splits a query into lowercase words.
+func queryTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termCoverage returns the fraction of terms that occur in text.
+func termCoverage(text string, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	text = strings.ToLower(text)
+	found := 0
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(terms))
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+)
+
+// ErrExists is returned by DownloadFile when the destination already exists
+// and overwriting was not requested.
+var ErrExists = errors.New("file already exists")
+
+// Download is an in-progress document download.
+type Download struct {
+	// Body is the document content starting at Offset. The caller must
+	// close it.
+	Body io.ReadCloser
+
+	// Filename is the server-suggested filename, if any.
+	Filename string
+
+	// Offset is the position of Body within the document. It is zero when
+	// the server sent the whole document, even if a range was requested.
+	Offset int64
+
+	// Size is the total document size, or -1 if unknown.
+	Size int64
+
+	// Validator is the ETag (or Last-Modified date) identifying this
+	// version of the document, for use with If-Range when resuming.
+	Validator string
+}
+
+// DownloadRange starts downloading a document from offset. When validator
+// is set it is sent as If-Range, so a server whose copy has changed since
+// the partial download began returns the whole document instead (reported
+// by a zero Offset). If the server reports that the document is exactly
+// offset bytes long, the download is already complete and Body is empty.
+func (c *Client) DownloadRange(ctx context.Context, id string, offset int64, validator string) (*Download, error) {
+	if c.offline {
+		return nil, fmt.Errorf("download %s: %w", id, ErrOffline)
+	}
+
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := c.send(ctx, "/documents/"+id+"/download", header, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing is left after offset: the partial copy is either complete
+	// or longer than the document, in which case start over.
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		resp.Body.Close()
+		if size, ok := unsatisfiedSize(resp.Header.Get("Content-Range")); ok && size == offset {
+			return &Download{
+				Body:      http.NoBody,
+				Offset:    offset,
+				Size:      size,
+				Validator: validator,
+			}, nil
+		}
+		return c.DownloadRange(ctx, id, 0, "")
+	}
+
+	dl := &Download{
+		Body:      resp.Body,
+		Filename:  dispositionFilename(resp.Header.Get("Content-Disposition")),
+		Size:      -1,
+		Validator: resp.Header.Get("ETag"),
+	}
+	if dl.Validator == "" {
+		dl.Validator = resp.Header.Get("Last-Modified")
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		dl.Size = resp.ContentLength
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		dl.Offset = start
+		dl.Size = size
+	default:
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+
+	return dl, nil
+}
+
+// DownloadOptions controls DownloadFile.
+type DownloadOptions struct {
+	// Resume continues from an existing partial download, if one exists.
+	// Otherwise any partial download is discarded.
+	Resume bool
+
+	// Overwrite replaces an existing destination file.
+	Overwrite bool
+
+	// Checksum, if set, is verified against the downloaded content. On a
+	// mismatch the file is moved to dest+".corrupt" and a
+	// *checksum.MismatchError is returned.
+	Checksum string
+}
+
+// partState is persisted next to a partial download so it can be resumed.
+type partState struct {
+	ID        string `json:"id"`
+	Validator string `json:"validator,omitempty"`
+}
+
+// DownloadFile downloads a document to dest. Data is written to
+// dest+".part" and renamed into place only once complete (and verified, if
+// a checksum is given), so dest never holds a truncated or corrupt file. If
+// the transfer fails the partial file is kept and a later call with Resume
+// set continues it using a Range request; a partial download the server
+// reports as complete is verified and moved into place without fetching it
+// again. It returns the number of bytes written by this call.
+func (c *Client) DownloadFile(ctx context.Context, id, dest string, opts DownloadOptions) (int64, error) {
+	var verifier *checksum.Verifier
+	if opts.Checksum != "" {
+		v, err := checksum.New(opts.Checksum)
+		if err != nil {
+			return 0, err
+		}
+		verifier = v
+	}
+
+	if !opts.Overwrite {
+		if _, err := os.Stat(dest); err == nil {
+			return 0, fmt.Errorf("%s: %w", dest, ErrExists)
+		}
+	}
+
+	partPath := dest + ".part"
+	statePath := partPath + ".json"
+
+	var offset int64
+	var state partState
+	if opts.Resume {
+		if info, err := os.Stat(partPath); err == nil {
+			if data, err := os.ReadFile(statePath); err == nil && json.Unmarshal(data, &state) == nil && state.ID == id {
+				offset = info.Size()
+			}
+		}
+	}
+	if offset == 0 {
+		state = partState{ID: id}
+	}
+
+	dl, err := c.DownloadRange(ctx, id, offset, state.Validator)
+	if err != nil {
+		return 0, err
+	}
+	defer dl.Body.Close()
+
+	flags := os.O_CREATE | os.O_RDWR
+	if dl.Offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	// Hash the bytes already on disk so resumed downloads verify in full.
+	var w io.Writer = file
+	if verifier != nil {
+		if _, err := io.CopyN(verifier, file, dl.Offset); err != nil {
+			file.Close()
+			return 0, fmt.Errorf("failed to read partial download: %w", err)
+		}
+		w = io.MultiWriter(file, verifier)
+	}
+	if _, err := file.Seek(dl.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	state.Validator = dl.Validator
+	if data, err := json.Marshal(state); err == nil {
+		_ = os.WriteFile(statePath, data, 0o644)
+	}
+
+	written, err := io.Copy(w, dl.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return written, fmt.Errorf("download interrupted: %w", err)
+	}
+	if dl.Size >= 0 && dl.Offset+written != dl.Size {
+		return written, fmt.Errorf("incomplete download: got %d of %d bytes", dl.Offset+written, dl.Size)
+	}
+
+	if verifier != nil {
+		if err := verifier.Verify(); err != nil {
+			_ = os.Rename(partPath, dest+".corrupt")
+			_ = os.Remove(statePath)
+			return written, fmt.Errorf("%w (quarantined as %s.corrupt)", err, dest)
+		}
+	}
+
+	if err := os.Rename(partPath, dest); err != nil {
+		return written, fmt.Errorf("failed to move file into place: %w", err)
+	}
+	_ = os.Remove(statePath)
+
+	return written, nil
+}
+
+// dispositionFilename extracts the filename from a Content-Disposition header.
+func dispositionFilename(cd string) string {
+	if cd == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(cd)
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// unsatisfiedSize parses the "bytes */size" Content-Range header of a 416
+// response.
+func unsatisfiedSize(v string) (int64, bool) {
+	total, found := strings.CutPrefix(v, "bytes */")
+	if !found {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	return size, err == nil
+}
+
+// parseContentRange parses a "bytes start-end/size" Content-Range header.
+// The size is -1 if the server reported it as unknown.
+func parseContentRange(v string) (start, size int64, ok bool) {
+	rest, found := strings.CutPrefix(v, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, total, found := strings.Cut(rest, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
@@ -0,0 +1,419 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+)
+
+// docContent is the document served by newDocServer.
+var docContent = []byte(strings.Repeat("0123456789", 100))
+
+// docETag is the ETag of docContent.
+const docETag = `"v1"`
+
+// docServer serves docContent at /documents/doc1/download, honoring Range
+// and If-Range unless ignoreRange is set, and records the requests.
+type docServer struct {
+	*httptest.Server
+	ignoreRange bool
+
+	mu       sync.Mutex
+	requests []http.Header
+}
+
+func newDocServer(t *testing.T) *docServer {
+	t.Helper()
+	s := &docServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Header.Clone())
+		s.mu.Unlock()
+
+		if r.URL.Path != "/api/"+APIVersion+"/documents/doc1/download" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", docETag)
+		w.Header().Set("Content-Disposition", `attachment; filename="doc1.pdf"`)
+		if s.ignoreRange {
+			w.Write(docContent)
+			return
+		}
+		http.ServeContent(w, r, "doc1.pdf", time.Time{}, bytes.NewReader(docContent))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// received returns the headers of the requests received so far.
+func (s *docServer) received() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// writePart leaves a partial download of document id at dest, with the
+// given content and validator.
+func writePart(t *testing.T, dest, id string, content []byte, validator string) {
+	t.Helper()
+	if err := os.WriteFile(dest+".part", content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(partState{ID: id, Validator: validator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".part.json", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkDownloaded checks that dest holds docContent and that no partial
+// download is left behind.
+func checkDownloaded(t *testing.T, dest string) {
+	t.Helper()
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, docContent) {
+		t.Errorf("downloaded %d bytes differing from the document's %d", len(got), len(docContent))
+	}
+	for _, leftover := range []string{dest + ".part", dest + ".part.json", dest + ".corrupt"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s was left behind", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	half := int64(len(docContent) / 2)
+	tests := []struct {
+		name        string
+		part        []byte // partial download left by an earlier call
+		partID      string
+		validator   string
+		resume      bool
+		ignoreRange bool
+		wantRange   string // of the first request
+		wantIfRange string
+		restarted   bool // a second request fetches the whole document
+		wantWritten int64
+	}{
+		{
+			name:        "fresh",
+			resume:      true,
+			wantWritten: int64(len(docContent)),
+		},
+		{
+			name:        "resumed",
+			part:        docContent[:half],
+			validator:   docETag,
+			resume:      true,
+			wantRange:   "bytes=500-",
+			wantIfRange: docETag,
+			wantWritten: int64(len(docContent)) - half,
+		},
+		{
+			name:        "resumed without validator",
+			part:        docContent[:half],
+			resume:      true,
+			wantRange:   "bytes=500-",
+			wantWritten: int64(len(docContent)) - half,
+		},
+		{
+			// If-Range fails, so the server sends the whole document.
+			name:        "changed since partial",
+			part:        bytes.Repeat([]byte("x"), int(half)),
+			validator:   `"v0"`,
+			resume:      true,
+			wantRange:   "bytes=500-",
+			wantIfRange: `"v0"`,
+			wantWritten: int64(len(docContent)),
+		},
+		{
+			name:        "server ignores range",
+			part:        bytes.Repeat([]byte("x"), int(half)),
+			validator:   docETag,
+			resume:      true,
+			ignoreRange: true,
+			wantRange:   "bytes=500-",
+			wantIfRange: docETag,
+			wantWritten: int64(len(docContent)),
+		},
+		{
+			// The partial file is longer than the document: 416, then
+			// the download starts over.
+			name:        "range not satisfiable",
+			part:        append(bytes.Clone(docContent), "extra"...),
+			validator:   docETag,
+			resume:      true,
+			wantRange:   "bytes=1005-",
+			wantIfRange: docETag,
+			restarted:   true,
+			wantWritten: int64(len(docContent)),
+		},
+		{
+			// The partial file is complete: 416, and it is moved into
+			// place without downloading it again.
+			name:        "partial already complete",
+			part:        bytes.Clone(docContent),
+			validator:   docETag,
+			resume:      true,
+			wantRange:   "bytes=1000-",
+			wantIfRange: docETag,
+			wantWritten: 0,
+		},
+		{
+			name:        "resume not requested",
+			part:        bytes.Repeat([]byte("x"), int(half)),
+			validator:   docETag,
+			wantWritten: int64(len(docContent)),
+		},
+		{
+			name:        "partial of another document",
+			part:        bytes.Repeat([]byte("x"), int(half)),
+			partID:      "doc2",
+			validator:   docETag,
+			resume:      true,
+			wantWritten: int64(len(docContent)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDocServer(t)
+			srv.ignoreRange = tt.ignoreRange
+			dest := filepath.Join(t.TempDir(), "doc1.pdf")
+			if tt.part != nil {
+				id := tt.partID
+				if id == "" {
+					id = "doc1"
+				}
+				writePart(t, dest, id, tt.part, tt.validator)
+			}
+
+			c := New(srv.URL, "key")
+			written, err := c.DownloadFile(context.Background(), "doc1", dest, DownloadOptions{Resume: tt.resume})
+			if err != nil {
+				t.Fatalf("DownloadFile: %v", err)
+			}
+			if written != tt.wantWritten {
+				t.Errorf("wrote %d bytes, want %d", written, tt.wantWritten)
+			}
+			checkDownloaded(t, dest)
+
+			requests := srv.received()
+			wantRequests := 1
+			if tt.restarted {
+				wantRequests = 2
+			}
+			if len(requests) != wantRequests {
+				t.Fatalf("made %d requests, want %d", len(requests), wantRequests)
+			}
+			first, last := requests[0], requests[len(requests)-1]
+			if got := first.Get("Range"); got != tt.wantRange {
+				t.Errorf("Range = %q, want %q", got, tt.wantRange)
+			}
+			if got := first.Get("If-Range"); got != tt.wantIfRange {
+				t.Errorf("If-Range = %q, want %q", got, tt.wantIfRange)
+			}
+			if tt.restarted && (last.Get("Range") != "" || last.Get("If-Range") != "") {
+				t.Errorf("restarted with Range %q, If-Range %q", last.Get("Range"), last.Get("If-Range"))
+			}
+		})
+	}
+}
+
+func TestDownloadFileExists(t *testing.T) {
+	srv := newDocServer(t)
+	dest := filepath.Join(t.TempDir(), "doc1.pdf")
+	if err := os.WriteFile(dest, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(srv.URL, "key")
+
+	if _, err := c.DownloadFile(context.Background(), "doc1", dest, DownloadOptions{}); !errors.Is(err, ErrExists) {
+		t.Fatalf("DownloadFile error = %v, want ErrExists", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("made %d requests for an existing file", n)
+	}
+
+	if _, err := c.DownloadFile(context.Background(), "doc1", dest, DownloadOptions{Overwrite: true}); err != nil {
+		t.Fatalf("DownloadFile with Overwrite: %v", err)
+	}
+	checkDownloaded(t, dest)
+}
+
+func TestDownloadRange(t *testing.T) {
+	srv := newDocServer(t)
+	c := New(srv.URL, "key")
+
+	dl, err := c.DownloadRange(context.Background(), "doc1", 990, docETag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Body.Close()
+	if dl.Offset != 990 || dl.Size != int64(len(docContent)) || dl.Validator != docETag || dl.Filename != "doc1.pdf" {
+		t.Errorf("got offset %d, size %d, validator %s, filename %q", dl.Offset, dl.Size, dl.Validator, dl.Filename)
+	}
+
+	if _, err := c.DownloadRange(context.Background(), "missing", 0, ""); !IsNotFound(err) {
+		t.Errorf("DownloadRange of a missing document: %v, want not found", err)
+	}
+}
+
+func TestDownloadRangeMismatch(t *testing.T) {
+	// A server answering with a range other than the one asked for.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-9/100")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(docContent[:10])
+	}))
+	defer srv.Close()
+
+	if _, err := New(srv.URL, "key").DownloadRange(context.Background(), "doc1", 50, ""); err == nil {
+		t.Error("DownloadRange accepted a mismatched Content-Range")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 0-99/100", 0, 100, true},
+		{"bytes 500-999/1000", 500, 1000, true},
+		{"bytes 500-999/*", 500, -1, true},
+		{"", 0, 0, false},
+		{"bytes */1000", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 0-9", 0, 0, false},
+		{"bytes x-9/10", 0, 0, false},
+		{"bytes 0-9/x", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.in)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v", tt.in, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
+
+func TestDownloadFileChecksum(t *testing.T) {
+	sum := sha256.Sum256(docContent)
+	good := hex.EncodeToString(sum[:])
+	bad := strings.Repeat("0", len(good))
+	half := len(docContent) / 2
+
+	tests := []struct {
+		name     string
+		checksum string
+		part     []byte // partial download to resume
+		wantErr  bool
+	}{
+		{name: "sha256", checksum: good},
+		{name: "prefixed", checksum: "sha256:" + good},
+		{name: "resumed", checksum: good, part: docContent[:half]},
+		{name: "complete partial", checksum: good, part: docContent},
+		{name: "complete corrupt partial", checksum: good, part: bytes.Repeat([]byte("x"), len(docContent)), wantErr: true},
+		{name: "mismatch", checksum: bad, wantErr: true},
+		// The bytes already on disk count towards the checksum.
+		{name: "corrupt partial", checksum: good, part: bytes.Repeat([]byte("x"), half), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDocServer(t)
+			dest := filepath.Join(t.TempDir(), "doc1.pdf")
+			if tt.part != nil {
+				writePart(t, dest, "doc1", tt.part, docETag)
+			}
+
+			c := New(srv.URL, "key")
+			_, err := c.DownloadFile(context.Background(), "doc1", dest, DownloadOptions{Resume: true, Checksum: tt.checksum})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("DownloadFile: %v", err)
+				}
+				checkDownloaded(t, dest)
+				return
+			}
+
+			var mismatch *checksum.MismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("DownloadFile error = %v, want *checksum.MismatchError", err)
+			}
+			if _, err := os.Stat(dest); err == nil {
+				t.Error("corrupt download was moved into place")
+			}
+			if _, err := os.Stat(dest + ".corrupt"); err != nil {
+				t.Errorf("corrupt download was not quarantined: %v", err)
+			}
+			for _, leftover := range []string{dest + ".part", dest + ".part.json"} {
+				if _, err := os.Stat(leftover); err == nil {
+					t.Errorf("%s was left behind", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		srv := newDocServer(t)
+		dest := filepath.Join(t.TempDir(), "doc1.pdf")
+		_, err := New(srv.URL, "key").DownloadFile(context.Background(), "doc1", dest, DownloadOptions{Checksum: "crc32:1234"})
+		if !errors.Is(err, checksum.ErrUnsupported) {
+			t.Errorf("DownloadFile error = %v, want ErrUnsupported", err)
+		}
+		if n := len(srv.received()); n != 0 {
+			t.Errorf("made %d requests with an unusable checksum", n)
+		}
+	})
+}
+
+func TestDownloadHeaderTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/slow/") {
+			time.Sleep(3 * timeout)
+		}
+		w.Write(docContent[:10])
+		w.(http.Flusher).Flush()
+		// The rest of the body takes longer than the timeout, which only
+		// bounds the wait for headers.
+		time.Sleep(3 * timeout)
+		w.Write(docContent[10:])
+	}))
+	defer srv.Close()
+	c := New(srv.URL, "key", WithTimeout(timeout), WithRetry(RetryPolicy{MaxAttempts: 1}))
+
+	dl, err := c.DownloadRange(context.Background(), "doc1", 0, "")
+	if err != nil {
+		t.Fatalf("DownloadRange: %v", err)
+	}
+	data, err := io.ReadAll(dl.Body)
+	dl.Body.Close()
+	if err != nil || !bytes.Equal(data, docContent) {
+		t.Errorf("read %d bytes, %v; want the whole document", len(data), err)
+	}
+
+	if _, err := c.DownloadRange(context.Background(), "slow", 0, ""); err == nil || !strings.Contains(err.Error(), "no response within") {
+		t.Errorf("DownloadRange with slow headers: %v, want a timeout", err)
+	}
+}
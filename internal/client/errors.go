@@ -0,0 +1,115 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// APIError is an error response returned by the Manuals API.
+type APIError struct {
+	// StatusCode is the HTTP status code.
+	StatusCode int `json:"status"`
+
+	// Code is the machine-readable error code, if the server provided one.
+	Code string `json:"code,omitempty"`
+
+	// Message is the human-readable error message.
+	Message string `json:"message"`
+
+	// RequestID identifies the request in server logs, if available.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	s := fmt.Sprintf("API error (%d): %s", e.StatusCode, msg)
+	if e.RequestID != "" {
+		s += fmt.Sprintf(" [request %s]", e.RequestID)
+	}
+	return s
+}
+
+// NetworkError is returned when a request could not be completed, e.g.
+// because the server was unreachable or did not respond in time.
+type NetworkError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *NetworkError) Error() string {
+	return "request failed: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound reports whether err is an API 404 Not Found error.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an API 401 Unauthorized error.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an API 403 Forbidden error.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether err is an API 429 Too Many Requests error.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsServerError reports whether err is an API 5xx error.
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 500
+}
+
+// IsNetwork reports whether err is a transport-level failure.
+func IsNetwork(err error) bool {
+	var netErr *NetworkError
+	return errors.As(err, &netErr)
+}
+
+// hasStatus reports whether err is an APIError with the given status.
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// newAPIError builds an APIError from a non-success response. The body is
+// decoded as an ErrorResponse when possible and used verbatim otherwise.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+		apiErr.Code = errResp.Code
+		if errResp.RequestID != "" {
+			apiErr.RequestID = errResp.RequestID
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
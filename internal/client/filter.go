@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// searchOverfetch is how many more results than the limit a filtered search
+// asks for, so results dropped by client-side filtering can be made up.
+const searchOverfetch = 4
+
+// maxSearchFetch caps the number of results a filtered search asks for.
+const maxSearchFetch = 100
+
+// SearchFilter narrows search results to a domain and type, a minimum
+// score and devices whose metadata matches every predicate. The zero value
+// matches everything.
+type SearchFilter struct {
+	Domain   string
+	Type     string
+	MinScore float64
+	Where    []Predicate
+}
+
+// IsZero reports whether f matches everything.
+func (f SearchFilter) IsZero() bool {
+	return f.Domain == "" && f.Type == "" && f.MinScore == 0 && len(f.Where) == 0
+}
+
+// params adds the filter to search query parameters.
+func (f SearchFilter) params(params url.Values) {
+	if f.Domain != "" {
+		params.Set("domain", f.Domain)
+	}
+	if f.Type != "" {
+		params.Set("type", f.Type)
+	}
+	if f.MinScore > 0 {
+		params.Set("min_score", strconv.FormatFloat(f.MinScore, 'f', -1, 64))
+	}
+	for _, p := range f.Where {
+		params.Add("where", p.String())
+	}
+}
+
+// Apply returns the results that match f, at most limit of them (all if
+// limit is 0). Where predicates need device metadata: results that carry
+// none are looked up with metadata, which is only called for results that
+// pass the other filters.
+func (f SearchFilter) Apply(results []SearchResult, limit int, metadata func(r SearchResult) (map[string]interface{}, error)) ([]SearchResult, error) {
+	matched, _, err := f.apply(results, limit, metadata)
+	return matched, err
+}
+
+// apply is Apply, also returning the number of results the filter
+// rejected.
+func (f SearchFilter) apply(results []SearchResult, limit int, metadata func(r SearchResult) (map[string]interface{}, error)) ([]SearchResult, int, error) {
+	matched := make([]SearchResult, 0, len(results))
+	rejected := 0
+	for _, r := range results {
+		if limit > 0 && len(matched) >= limit {
+			break
+		}
+		if f.Domain != "" && !strings.EqualFold(r.Domain, f.Domain) ||
+			f.Type != "" && !strings.EqualFold(r.Type, f.Type) ||
+			r.Score < f.MinScore {
+			rejected++
+			continue
+		}
+		if len(f.Where) > 0 {
+			md := r.Metadata
+			if md == nil && metadata != nil {
+				var err error
+				if md, err = metadata(r); err != nil {
+					return nil, 0, err
+				}
+			}
+			if !MatchAll(f.Where, md) {
+				rejected++
+				continue
+			}
+		}
+		matched = append(matched, r)
+	}
+	return matched, rejected, nil
+}
+
+// Predicate is a condition on a device metadata field, such as
+// vendor=espressif or pins>=30.
+type Predicate struct {
+	// Key is the metadata field, a dotted path for nested values.
+	Key string
+
+	// Op is one of =, !=, ~ (contains), <, <=, > or >=.
+	Op string
+
+	// Value is compared numerically when both sides are numbers, and
+	// otherwise as text, ignoring case.
+	Value string
+}
+
+// predicateOps lists the predicate operators. ParsePredicate splits at the
+// first operator, preferring the longer of two at the same position so
+// that >= is not read as >.
+var predicateOps = []string{"!=", "<=", ">=", "=", "~", "<", ">"}
+
+// ParsePredicate parses a predicate of the form key<op>value.
+func ParsePredicate(s string) (Predicate, error) {
+	at, op := -1, ""
+	for _, o := range predicateOps {
+		if i := strings.Index(s, o); i >= 0 && (at < 0 || i < at || i == at && len(o) > len(op)) {
+			at, op = i, o
+		}
+	}
+	if at <= 0 {
+		return Predicate{}, fmt.Errorf("invalid predicate %q (use key=value, key!=value, key~text, key<n, key<=n, key>n or key>=n)", s)
+	}
+	return Predicate{
+		Key:   strings.TrimSpace(s[:at]),
+		Op:    op,
+		Value: strings.TrimSpace(s[at+len(op):]),
+	}, nil
+}
+
+// String returns the predicate in the form ParsePredicate accepts.
+func (p Predicate) String() string {
+	return p.Key + p.Op + p.Value
+}
+
+// Match reports whether metadata satisfies the predicate. A list value
+// matches if any element does; a missing value only matches !=.
+func (p Predicate) Match(metadata map[string]interface{}) bool {
+	var v interface{} = metadata
+	for _, key := range strings.Split(p.Key, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			v = nil
+			break
+		}
+		v = lookupKey(m, key)
+	}
+
+	if list, ok := v.([]interface{}); ok {
+		if p.Op == "!=" {
+			for _, e := range list {
+				if !p.matchValue(e) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, e := range list {
+			if p.matchValue(e) {
+				return true
+			}
+		}
+		return false
+	}
+	if v == nil {
+		return p.Op == "!="
+	}
+	return p.matchValue(v)
+}
+
+// matchValue compares a single metadata value.
+func (p Predicate) matchValue(v interface{}) bool {
+	text := fmt.Sprint(v)
+	a, errA := strconv.ParseFloat(text, 64)
+	b, errB := strconv.ParseFloat(p.Value, 64)
+	numeric := errA == nil && errB == nil
+
+	cmp := 0
+	if numeric {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(text), strings.ToLower(p.Value))
+	}
+
+	switch p.Op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "~":
+		return strings.Contains(strings.ToLower(text), strings.ToLower(p.Value))
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// lookupKey returns m[key], matching the key without regard to case if
+// there is no exact match.
+func lookupKey(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// MatchAll reports whether metadata satisfies every predicate.
+func MatchAll(preds []Predicate, metadata map[string]interface{}) bool {
+	for _, p := range preds {
+		if !p.Match(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchFilteredContext searches for devices matching filter. The filters
+// are sent to the server and, since servers that do not support them
+// ignore them, also applied to the results; more results than limit are
+// asked for so that enough remain. Metadata predicates look up each
+// candidate device unless the server includes its metadata. If results
+// were filtered out here, Total is reduced by their number.
+func (c *Client) SearchFilteredContext(ctx context.Context, query string, limit int, filter SearchFilter) (*SearchResponse, error) {
+	if filter.IsZero() {
+		return c.SearchContext(ctx, query, limit)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(max(min(limit*searchOverfetch, maxSearchFetch), limit)))
+	}
+	filter.params(params)
+
+	var resp SearchResponse
+	if err := c.get(ctx, "/search?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	results, rejected, err := filter.apply(resp.Results, limit, func(r SearchResult) (map[string]interface{}, error) {
+		d, err := c.GetDeviceContext(ctx, r.DeviceID)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", r.DeviceID, err)
+		}
+		return d.Metadata, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The server's total counts results it did not filter out.
+	if rejected > 0 {
+		resp.Total = max(resp.Total-rejected, len(results))
+	}
+	resp.Results = results
+	return &resp, nil
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Predicate
+	}{
+		{"vendor=espressif", Predicate{"vendor", "=", "espressif"}},
+		{"vendor!=espressif", Predicate{"vendor", "!=", "espressif"}},
+		{"name~wroom", Predicate{"name", "~", "wroom"}},
+		{"pins<30", Predicate{"pins", "<", "30"}},
+		{"pins<=30", Predicate{"pins", "<=", "30"}},
+		{"pins>30", Predicate{"pins", ">", "30"}},
+		{"pins>=30", Predicate{"pins", ">=", "30"}},
+		{" pins >= 30 ", Predicate{"pins", ">=", "30"}},
+		{"specs.flash=4MB", Predicate{"specs.flash", "=", "4MB"}},
+		{"url=http://x/?a=b", Predicate{"url", "=", "http://x/?a=b"}},
+		{"note~a<b", Predicate{"note", "~", "a<b"}},
+		{"vendor=", Predicate{"vendor", "=", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePredicate(tt.in)
+			if err != nil {
+				t.Fatalf("ParsePredicate(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePredicate(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if again, err := ParsePredicate(got.String()); err != nil || again != got {
+				t.Errorf("ParsePredicate(%q) = %+v, %v; want it to round-trip", got.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestParsePredicateErrors(t *testing.T) {
+	for _, in := range []string{"", "vendor", "=espressif", ">=3", "~x"} {
+		if p, err := ParsePredicate(in); err == nil {
+			t.Errorf("ParsePredicate(%q) = %+v, want error", in, p)
+		}
+	}
+}
+
+// predicateMetadata is the device metadata the predicate tests match.
+var predicateMetadata = map[string]interface{}{
+	"Vendor":  "Espressif",
+	"pins":    float64(38),
+	"voltage": "3.3",
+	"package": "QFN",
+	"tags":    []interface{}{"wifi", "bluetooth"},
+	"specs":   map[string]interface{}{"flash_mb": float64(4), "core": "Xtensa"},
+	"enabled": true,
+}
+
+func TestPredicateMatch(t *testing.T) {
+	tests := []struct {
+		pred string
+		want bool
+	}{
+		// Text compares ignoring case, and keys match without regard to
+		// case if there is no exact match.
+		{"vendor=espressif", true},
+		{"VENDOR=ESPRESSIF", true},
+		{"vendor=nordic", false},
+		{"vendor!=nordic", true},
+		{"vendor~press", true},
+		{"vendor~nordic", false},
+		{"vendor<f", true},
+		{"vendor>f", false},
+
+		// Numbers compare numerically, even when stored as text.
+		{"pins=38", true},
+		{"pins=38.0", true},
+		{"pins>=30", true},
+		{"pins>38", false},
+		{"pins<100", true},
+		{"pins<=37", false},
+		{"voltage>3", true},
+		{"voltage<=3.3", true},
+		{"package<R", true},
+
+		// Lists match if any element does; != if none does.
+		{"tags=wifi", true},
+		{"tags=zigbee", false},
+		{"tags~blue", true},
+		{"tags!=zigbee", true},
+		{"tags!=wifi", false},
+
+		// Nested values.
+		{"specs.flash_mb>=4", true},
+		{"specs.flash_mb<4", false},
+		{"specs.core=xtensa", true},
+		{"specs.missing=1", false},
+		{"pins.nested=1", false},
+
+		// Missing values only match !=.
+		{"missing=x", false},
+		{"missing~x", false},
+		{"missing!=x", true},
+
+		{"enabled=true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pred, func(t *testing.T) {
+			p, err := ParsePredicate(tt.pred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Match(predicateMetadata); got != tt.want {
+				t.Errorf("%s matched %v, want %v", tt.pred, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFilterApply(t *testing.T) {
+	results := []SearchResult{
+		{DeviceID: "a", Domain: "hardware", Type: "sensors", Score: 0.9},
+		{DeviceID: "b", Domain: "hardware", Type: "boards", Score: 0.8},
+		{DeviceID: "c", Domain: "software", Type: "sensors", Score: 0.7},
+		{DeviceID: "d", Domain: "Hardware", Type: "Sensors", Score: 0.4},
+		{DeviceID: "e", Domain: "hardware", Type: "sensors", Score: 0.3,
+			Metadata: map[string]interface{}{"vendor": "bosch"}},
+	}
+	metadata := map[string]map[string]interface{}{
+		"a": {"vendor": "bosch"},
+		"b": {"vendor": "espressif"},
+		"c": {"vendor": "bosch"},
+		"d": {"vendor": "sensirion"},
+	}
+
+	where := func(s string) []Predicate {
+		p, err := ParsePredicate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []Predicate{p}
+	}
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		limit  int
+		want   string
+	}{
+		{"zero", SearchFilter{}, 0, "abcde"},
+		{"limit", SearchFilter{}, 2, "ab"},
+		{"domain ignores case", SearchFilter{Domain: "HARDWARE"}, 0, "abde"},
+		{"type", SearchFilter{Type: "sensors"}, 0, "acde"},
+		{"min score", SearchFilter{MinScore: 0.7}, 0, "abc"},
+		{"where", SearchFilter{Where: where("vendor=bosch")}, 0, "ace"},
+		{"combined", SearchFilter{Domain: "hardware", Where: where("vendor=bosch")}, 0, "ae"},
+		{"limit after filtering", SearchFilter{Type: "sensors"}, 2, "ac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var looked []string
+			got, err := tt.filter.Apply(results, tt.limit, func(r SearchResult) (map[string]interface{}, error) {
+				looked = append(looked, r.DeviceID)
+				return metadata[r.DeviceID], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := ""
+			for _, r := range got {
+				ids += r.DeviceID
+			}
+			if ids != tt.want {
+				t.Errorf("Apply matched %q, want %q", ids, tt.want)
+			}
+			for _, id := range looked {
+				if id == "e" {
+					t.Errorf("looked up metadata of a result that carries it")
+				}
+			}
+		})
+	}
+
+	t.Run("metadata error", func(t *testing.T) {
+		want := errors.New("lookup failed")
+		_, err := SearchFilter{Where: where("vendor=bosch")}.Apply(results, 0, func(SearchResult) (map[string]interface{}, error) {
+			return nil, want
+		})
+		if !errors.Is(err, want) {
+			t.Errorf("Apply error = %v, want %v", err, want)
+		}
+	})
+}
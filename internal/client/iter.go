@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// DefaultPageSize is the page size used by the iterators when none is given.
+const DefaultPageSize = 100
+
+// AllDevices returns an iterator over every device matching the filters.
+// Pages of pageSize devices are fetched on demand as iteration proceeds, so
+// the full catalog is never held in memory. If a page fails to load, the
+// error is yielded with a zero Device and iteration stops.
+func (c *Client) AllDevices(ctx context.Context, pageSize int, domain, deviceType string) iter.Seq2[Device, error] {
+	return paginate(pageSize, func(limit, offset int) ([]Device, int, error) {
+		resp, err := c.ListDevicesContext(ctx, limit, offset, domain, deviceType)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data, resp.Total, nil
+	})
+}
+
+// AllDocuments returns an iterator over every document, optionally limited
+// to a single device. It pages through results like AllDevices.
+func (c *Client) AllDocuments(ctx context.Context, pageSize int, deviceID string) iter.Seq2[Document, error] {
+	return paginate(pageSize, func(limit, offset int) ([]Document, int, error) {
+		resp, err := c.ListDocumentsContext(ctx, limit, offset, deviceID)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data, resp.Total, nil
+	})
+}
+
+// paginate walks a paginated endpoint using the Total/Offset convention of
+// the list endpoints. fetch returns one page and the total result count.
+func paginate[T any](pageSize int, fetch func(limit, offset int) ([]T, int, error)) iter.Seq2[T, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	return func(yield func(T, error) bool) {
+		offset := 0
+		for {
+			items, total, err := fetch(pageSize, offset)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			offset += len(items)
+			if len(items) == 0 || offset >= total {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxCandidates bounds the candidates listed in an AmbiguousError.
+const maxCandidates = 10
+
+// AmbiguousError is returned when an identifier matches more than one
+// device or document.
+type AmbiguousError struct {
+	// Kind is "device" or "document".
+	Kind string
+
+	// Ref is the identifier as given.
+	Ref string
+
+	// Candidates describes the matches, as "<id>  <name>", up to
+	// maxCandidates of them.
+	Candidates []string
+
+	// Count is the total number of matches.
+	Count int
+}
+
+// Error implements the error interface.
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d %ss; use a longer ID:", e.Ref, e.Count, e.Kind)
+	for _, c := range e.Candidates {
+		b.WriteString("\n  " + c)
+	}
+	if more := e.Count - len(e.Candidates); more > 0 {
+		fmt.Fprintf(&b, "\n  ... and %d more", more)
+	}
+	return b.String()
+}
+
+// ResolveDevice gets the device identified by ref, which may be a full
+// device ID, a unique ID prefix (as printed by list tables), a device name
+// or a device path. Names and paths are matched case-insensitively and take
+// precedence over ID prefixes.
+//
+// ref is first looked up directly as an ID. If that finds nothing, the
+// device list is scanned, which is served from the response cache when one
+// is configured. If ref matches nothing, the error of the direct lookup is
+// returned; if it matches several devices, an *AmbiguousError is returned.
+func (c *Client) ResolveDevice(ctx context.Context, ref string) (*Device, error) {
+	device, err := c.GetDeviceContext(ctx, url.PathEscape(ref))
+	if !canResolve(err) {
+		return device, err
+	}
+
+	var exact, prefix []Device
+	for d, listErr := range c.AllDevices(ctx, 0, "", "") {
+		if listErr != nil {
+			return nil, listErr
+		}
+		switch {
+		case strings.EqualFold(d.Name, ref), strings.EqualFold(d.Path, ref):
+			exact = append(exact, d)
+		case strings.HasPrefix(d.ID, ref):
+			prefix = append(prefix, d)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = prefix
+	}
+	switch len(matches) {
+	case 0:
+		return nil, err
+	case 1:
+		// List entries omit content, so fetch the full device.
+		return c.GetDeviceContext(ctx, matches[0].ID)
+	default:
+		return nil, ambiguous("device", ref, matches, func(d Device) string {
+			return d.ID + "  " + d.Name
+		})
+	}
+}
+
+// ResolveDocument gets the document identified by ref, which may be a full
+// document ID or a unique ID prefix. It resolves prefixes like
+// ResolveDevice.
+func (c *Client) ResolveDocument(ctx context.Context, ref string) (*Document, error) {
+	doc, err := c.GetDocumentContext(ctx, url.PathEscape(ref))
+	if !canResolve(err) {
+		return doc, err
+	}
+
+	var matches []Document
+	for d, listErr := range c.AllDocuments(ctx, 0, "") {
+		if listErr != nil {
+			return nil, listErr
+		}
+		if strings.HasPrefix(d.ID, ref) {
+			matches = append(matches, d)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, err
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, ambiguous("document", ref, matches, func(d Document) string {
+			return d.ID + "  " + d.Filename
+		})
+	}
+}
+
+// canResolve reports whether a failed direct lookup should fall back to
+// matching against the list: the ID was not found, or it is not cached
+// in offline mode.
+func canResolve(err error) bool {
+	return IsNotFound(err) || errors.Is(err, ErrOffline)
+}
+
+// ambiguous builds an AmbiguousError for matches, describing each with
+// describe.
+func ambiguous[T any](kind, ref string, matches []T, describe func(T) string) *AmbiguousError {
+	e := &AmbiguousError{Kind: kind, Ref: ref, Count: len(matches)}
+	for _, m := range matches[:min(len(matches), maxCandidates)] {
+		e.Candidates = append(e.Candidates, describe(m))
+	}
+	return e
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// catalogServer serves devices and documents by ID and in paginated lists.
+func catalogServer(t *testing.T, devices []Device, docs []Document) *httptest.Server {
+	t.Helper()
+	page := func(w http.ResponseWriter, r *http.Request, total int, data func(lo, hi int) any) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		lo, hi := min(offset, total), min(offset+limit, total)
+		json.NewEncoder(w).Encode(map[string]any{"data": data(lo, hi), "total": total, "limit": limit, "offset": offset})
+	}
+	notFound := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}
+
+	mux := http.NewServeMux()
+	prefix := "/api/" + APIVersion
+	mux.HandleFunc("GET "+prefix+"/devices", func(w http.ResponseWriter, r *http.Request) {
+		page(w, r, len(devices), func(lo, hi int) any { return devices[lo:hi] })
+	})
+	mux.HandleFunc("GET "+prefix+"/devices/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, d := range devices {
+			if d.ID == r.PathValue("id") {
+				d.Content = "content of " + d.ID
+				json.NewEncoder(w).Encode(d)
+				return
+			}
+		}
+		notFound(w)
+	})
+	mux.HandleFunc("GET "+prefix+"/documents", func(w http.ResponseWriter, r *http.Request) {
+		page(w, r, len(docs), func(lo, hi int) any { return docs[lo:hi] })
+	})
+	mux.HandleFunc("GET "+prefix+"/documents/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, d := range docs {
+			if d.ID == r.PathValue("id") {
+				json.NewEncoder(w).Encode(d)
+				return
+			}
+		}
+		notFound(w)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveDevice(t *testing.T) {
+	devices := []Device{
+		{ID: "a1b2c3d4", Name: "ESP32 DevKit", Path: "hardware/mcu/esp32"},
+		{ID: "a1b2ffff", Name: "BME280", Path: "hardware/sensors/bme280"},
+		{ID: "c0ffee00", Name: "SHT31", Path: "hardware/sensors/sht31"},
+		// A name that is another device's ID prefix.
+		{ID: "d00d0000", Name: "c0ff", Path: "hardware/misc/c0ff"},
+	}
+	c := New(catalogServer(t, devices, nil).URL, "key")
+
+	tests := []struct {
+		ref           string
+		wantID        string
+		wantAmbiguous []string
+		wantNotFound  bool
+	}{
+		{ref: "a1b2c3d4", wantID: "a1b2c3d4"},
+		{ref: "a1b2c", wantID: "a1b2c3d4"},
+		{ref: "esp32 devkit", wantID: "a1b2c3d4"},
+		{ref: "hardware/sensors/BME280", wantID: "a1b2ffff"},
+		{ref: "c0ff", wantID: "d00d0000"},
+		{ref: "a1b2", wantAmbiguous: []string{"a1b2c3d4  ESP32 DevKit", "a1b2ffff  BME280"}},
+		{ref: "ffff", wantNotFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			d, err := c.ResolveDevice(context.Background(), tt.ref)
+			var ambig *AmbiguousError
+			switch {
+			case tt.wantAmbiguous != nil:
+				if !errors.As(err, &ambig) {
+					t.Fatalf("error %v, want *AmbiguousError", err)
+				}
+				if ambig.Kind != "device" || ambig.Ref != tt.ref || ambig.Count != len(tt.wantAmbiguous) || !slices.Equal(ambig.Candidates, tt.wantAmbiguous) {
+					t.Errorf("AmbiguousError = %+v", ambig)
+				}
+			case tt.wantNotFound:
+				if !IsNotFound(err) {
+					t.Errorf("error %v, want not found", err)
+				}
+			case err != nil:
+				t.Fatalf("ResolveDevice: %v", err)
+			case d.ID != tt.wantID:
+				t.Errorf("resolved %s, want %s", d.ID, tt.wantID)
+			case d.Content == "":
+				t.Error("resolved device has no content")
+			}
+		})
+	}
+}
+
+func TestResolveDocument(t *testing.T) {
+	var docs []Document
+	for i := range 250 {
+		docs = append(docs, Document{ID: fmt.Sprintf("%03df00d", i), Filename: fmt.Sprintf("doc%d.pdf", i)})
+	}
+	c := New(catalogServer(t, nil, docs).URL, "key")
+
+	// Prefixes are matched across every page of the list.
+	if d, err := c.ResolveDocument(context.Background(), "249f"); err != nil || d.Filename != "doc249.pdf" {
+		t.Errorf("ResolveDocument(249f) = %+v, %v", d, err)
+	}
+	if d, err := c.ResolveDocument(context.Background(), "007f00d"); err != nil || d.Filename != "doc7.pdf" {
+		t.Errorf("ResolveDocument(007f00d) = %+v, %v", d, err)
+	}
+
+	_, err := c.ResolveDocument(context.Background(), "1")
+	var ambig *AmbiguousError
+	if !errors.As(err, &ambig) {
+		t.Fatalf("error %v, want *AmbiguousError", err)
+	}
+	if ambig.Kind != "document" || ambig.Count != 100 || len(ambig.Candidates) != maxCandidates || ambig.Candidates[0] != "100f00d  doc100.pdf" {
+		t.Errorf("AmbiguousError = %+v", ambig)
+	}
+	if msg := ambig.Error(); !strings.HasPrefix(msg, `"1" matches 100 documents; use a longer ID:`) || !strings.HasSuffix(msg, "... and 90 more") {
+		t.Errorf("message %q", msg)
+	}
+
+	if _, err := c.ResolveDocument(context.Background(), "zz"); !IsNotFound(err) {
+		t.Errorf("error %v, want not found", err)
+	}
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		full    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{9, time.Second},
+	}
+	for _, tt := range tests {
+		// Equal jitter: between half and all of the full delay.
+		for range 20 {
+			if d := p.backoff(tt.attempt); d < tt.full/2 || d > tt.full {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.full/2, tt.full)
+			}
+		}
+	}
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("backoff without a base delay = %s, want 0", d)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+		{http.StatusServiceUnavailable, true},
+		{http.StatusGatewayTimeout, true},
+	}
+	for _, tt := range tests {
+		if got := retryable(&http.Response{StatusCode: tt.status}, nil); got != tt.want {
+			t.Errorf("retryable(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+	if !retryable(nil, errors.New("connection reset")) {
+		t.Error("network errors are not retried")
+	}
+	if retryable(nil, errRequestBuild) {
+		t.Error("request build errors are retried")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"seconds", http.StatusTooManyRequests, "5", 5 * time.Second, true},
+		{"503", http.StatusServiceUnavailable, "2", 2 * time.Second, true},
+		{"past date", http.StatusTooManyRequests, "Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+		{"missing", http.StatusTooManyRequests, "", 0, false},
+		{"invalid", http.StatusTooManyRequests, "soon", 0, false},
+		{"negative", http.StatusTooManyRequests, "-1", 0, false},
+		{"other status", http.StatusBadGateway, "5", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(resp)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter = %s, %v; want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {future}}}
+	if d, ok := retryAfter(resp); !ok || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("retryAfter(%s) = %s, %v; want about an hour", future, d, ok)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // responses with status before a 200
+		status       int
+		retryAfter   string
+		wantAttempts int32
+		wantErr      bool
+		wantMinWait  time.Duration
+	}{
+		{"success", 0, 0, "", 1, false, 0},
+		{"recovers", 2, http.StatusServiceUnavailable, "", 3, false, 0},
+		// The hour-long Retry-After is capped at MaxDelay, which is still
+		// longer than the backoff.
+		{"honors capped Retry-After", 1, http.StatusTooManyRequests, "3600", 2, false, 10 * time.Millisecond},
+		{"gives up", 5, http.StatusBadGateway, "", 3, true, 0},
+		{"not retryable", 5, http.StatusNotFound, "", 1, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if n := attempts.Add(1); int(n) <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte(`{"id":"dev1"}`))
+			}))
+			defer srv.Close()
+
+			c := New(srv.URL, "key", WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+			start := time.Now()
+			_, err := c.GetDeviceContext(context.Background(), "dev1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetDevice error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if elapsed := time.Since(start); elapsed < tt.wantMinWait || elapsed > 5*time.Second {
+				t.Errorf("took %s, want at least %s and not the whole Retry-After", elapsed, tt.wantMinWait)
+			}
+			var apiErr *APIError
+			if tt.wantErr && !errors.As(err, &apiErr) {
+				t.Errorf("error %v, want *APIError", err)
+			} else if tt.wantErr && apiErr.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", apiErr.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestSendStopsWithContext(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", strconv.Itoa(60))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c := New(srv.URL, "key", WithRetry(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Minute}))
+	_, err := c.GetDeviceContext(ctx, "dev1")
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v, want a NetworkError for the deadline", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("made %d attempts, want 1", got)
+	}
+}
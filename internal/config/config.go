@@ -2,10 +2,16 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/rmrfslashbin/manuals-cli/internal/xdg"
 	"github.com/spf13/viper"
 )
 
@@ -17,65 +23,348 @@ type Config struct {
 	// APIKey is the API key for authentication.
 	APIKey string `mapstructure:"api_key"`
 
+	// APIKeyCommand is a shell command whose output is the API key, used
+	// when APIKey is not set (e.g. "pass show manuals").
+	APIKeyCommand string `mapstructure:"api_key_command"`
+
+	// CredentialStore selects where `manuals auth login` keeps API keys:
+	// auto, secret-service or file.
+	CredentialStore string `mapstructure:"credential_store"`
+
 	// OutputFormat is the default output format (json, table, text).
 	OutputFormat string `mapstructure:"output_format"`
+
+	// Timeout is the per-request deadline (e.g. 30s, 2m). Zero disables it.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Retries is the number of times a failed idempotent request is retried.
+	Retries int `mapstructure:"retries"`
+
+	// RetryWait is the initial backoff between retries; it doubles each time.
+	RetryWait time.Duration `mapstructure:"retry_wait"`
+
+	// RetryMaxWait caps the backoff, including server Retry-After delays.
+	RetryMaxWait time.Duration `mapstructure:"retry_max_wait"`
+
+	// CacheDir is the response cache directory (default: XDG cache dir).
+	CacheDir string `mapstructure:"cache_dir"`
+
+	// CacheTTL is how long cached responses are served without
+	// revalidation. Zero revalidates on every request.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+
+	// Color selects colored output: auto, always or never.
+	Color string `mapstructure:"color"`
+
+	// Pager is the command used to page long output (default: $PAGER or
+	// less).
+	Pager string `mapstructure:"pager"`
+
+	// SnippetLength is the length, in terminal columns, of search result
+	// snippets. Zero shows them in full.
+	SnippetLength int `mapstructure:"snippet_length"`
+
+	// MirrorDir is where `manuals sync` mirrors the catalog (default: XDG
+	// data dir).
+	MirrorDir string `mapstructure:"mirror_dir"`
+
+	// Profile is the name of the active profile, if any.
+	Profile string `mapstructure:"-"`
+
+	// File is the config file that was read, if any.
+	File string `mapstructure:"-"`
+
+	// Ignored lists the command keys set in File that were not used
+	// because the file is not trusted (see Trusted).
+	Ignored []string `mapstructure:"-"`
 }
 
-// Load reads configuration from file and environment.
-func Load() (*Config, error) {
-	v := viper.New()
+// Environment variables that control how configuration is loaded.
+const (
+	// ConfigEnv names the config file to read.
+	ConfigEnv = "MANUALS_CONFIG"
 
-	// Set defaults
-	v.SetDefault("api_url", "http://localhost:8080")
-	v.SetDefault("output_format", "table")
+	// ProfileEnv selects a profile.
+	ProfileEnv = "MANUALS_PROFILE"
+)
 
-	// Config file locations
-	v.SetConfigName(".manuals")
-	v.SetConfigType("yaml")
+// Key describes a configuration key.
+type Key struct {
+	// Name is the key as written in the config file.
+	Name string
 
-	// Look in home directory
-	if home, err := os.UserHomeDir(); err == nil {
-		v.AddConfigPath(home)
-	}
+	// Env is the environment variable that overrides the key.
+	Env string
 
-	// Look in current directory
-	v.AddConfigPath(".")
+	// Default is the value used when the key is not set anywhere.
+	Default string
 
-	// Also check XDG config
-	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
-		v.AddConfigPath(filepath.Join(xdgConfig, "manuals"))
-	} else if home, err := os.UserHomeDir(); err == nil {
-		v.AddConfigPath(filepath.Join(home, ".config", "manuals"))
-	}
+	// Description is a short explanation, used in the starter config.
+	Description string
 
-	// Environment variables
-	v.SetEnvPrefix("MANUALS")
-	v.AutomaticEnv()
+	// Secret marks keys whose values are redacted when displayed.
+	Secret bool
 
-	// Bind specific environment variables to config keys
-	_ = v.BindEnv("api_url", "MANUALS_API_URL")
-	_ = v.BindEnv("api_key", "MANUALS_API_KEY")
-	_ = v.BindEnv("output_format", "MANUALS_OUTPUT_FORMAT")
+	// Values lists the accepted values of enumerated keys.
+	Values []string
 
-	// Read config file (ignore if not found)
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config: %w", err)
+	// Command marks keys whose values are run through the shell. Only
+	// trusted config files can set them.
+	Command bool
+}
+
+// Keys lists every configuration key, in display order.
+var Keys = []Key{
+	{Name: "api_url", Env: "MANUALS_API_URL", Default: "http://localhost:8080", Description: "base URL of the Manuals API"},
+	{Name: "api_key", Env: "MANUALS_API_KEY", Description: "API key for authentication", Secret: true},
+	{Name: "api_key_command", Env: "MANUALS_API_KEY_COMMAND", Description: "command that prints the API key, used if api_key is unset", Command: true},
+	{Name: "credential_store", Env: "MANUALS_CREDENTIAL_STORE", Default: "auto", Description: "where 'auth login' stores keys (auto, secret-service, file)", Values: []string{"auto", "secret-service", "file"}},
+	{Name: "output_format", Env: "MANUALS_OUTPUT_FORMAT", Default: "table", Description: "default output format (table, wide, json, text, yaml, csv, tsv, ndjson, markdown)", Values: []string{"table", "wide", "json", "text", "yaml", "yml", "csv", "tsv", "ndjson", "jsonl", "markdown", "md"}},
+	{Name: "timeout", Env: "MANUALS_TIMEOUT", Default: "30s", Description: "per-request deadline; 0 disables"},
+	{Name: "retries", Env: "MANUALS_RETRIES", Default: "2", Description: "retries for transient failures (429, 502-504, network)"},
+	{Name: "retry_wait", Env: "MANUALS_RETRY_WAIT", Default: "500ms", Description: "initial retry backoff, doubled per retry"},
+	{Name: "retry_max_wait", Env: "MANUALS_RETRY_MAX_WAIT", Default: "10s", Description: "retry backoff cap, also applied to Retry-After"},
+	{Name: "color", Env: "MANUALS_COLOR", Default: "auto", Description: "colored output (auto, always, never); NO_COLOR disables auto", Values: []string{"auto", "always", "never"}},
+	{Name: "pager", Env: "MANUALS_PAGER", Description: "pager for long terminal output (default: $PAGER or less)", Command: true},
+	{Name: "snippet_length", Env: "MANUALS_SNIPPET_LENGTH", Default: "200", Description: "length of search result snippets; 0 shows them in full"},
+	{Name: "cache_dir", Env: "MANUALS_CACHE_DIR", Description: "response cache directory (default: XDG cache dir)"},
+	{Name: "cache_ttl", Env: "MANUALS_CACHE_TTL", Default: "5m", Description: "how long cached responses are used without revalidation"},
+	{Name: "mirror_dir", Env: "MANUALS_MIRROR_DIR", Description: "catalog mirror directory for sync (default: XDG data dir)"},
+}
+
+// LookupKey returns the key with the given name.
+func LookupKey(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
 		}
 	}
+	return Key{}, false
+}
+
+// Options controls how configuration is loaded.
+type Options struct {
+	// File is the config file to read. If empty, MANUALS_CONFIG is used,
+	// and otherwise the default locations are searched (see FindFile).
+	File string
+
+	// Profile is the profile to apply. If empty, MANUALS_PROFILE is used,
+	// and otherwise the file's current_profile.
+	Profile string
+
+	// AllowMissingProfile tolerates a selected profile that is not
+	// defined, e.g. so it can be created with 'config set'.
+	AllowMissingProfile bool
+}
+
+// Load reads configuration from file and environment. Settings of the
+// selected profile override the top-level settings of the config file;
+// environment variables override both.
+func Load(opts Options) (*Config, error) {
+	v, profile, err := load(opts)
+	if err != nil {
+		return nil, err
+	}
+	ignored := ignoreCommands(opts, v)
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("error parsing config: %w", err)
 	}
+	cfg.Profile = profile
+	cfg.File = v.ConfigFileUsed()
+	cfg.Ignored = ignored
+
+	if cfg.CacheDir == "" {
+		if dir, err := xdg.CacheDir(); err == nil {
+			cfg.CacheDir = dir
+		}
+	}
+	if cfg.MirrorDir == "" {
+		if dir, err := xdg.DataDir(); err == nil {
+			cfg.MirrorDir = dir
+		}
+	}
 
 	return &cfg, nil
 }
 
+// load reads the config file and applies the selected profile, returning
+// the resulting viper instance and the profile name.
+func load(opts Options) (*viper.Viper, string, error) {
+	v, err := readFile(opts.File)
+	if err != nil {
+		return nil, "", err
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile == "" {
+		profile = v.GetString("current_profile")
+	}
+	if profile != "" && v.IsSet("profiles."+profile) {
+		if err := v.MergeConfigMap(v.GetStringMap("profiles." + profile)); err != nil {
+			return nil, "", fmt.Errorf("error applying profile %q: %w", profile, err)
+		}
+	} else if profile != "" && !opts.AllowMissingProfile {
+		return nil, "", fmt.Errorf("profile %q not found in config", profile)
+	}
+
+	return v, profile, nil
+}
+
+// ignoreCommands clears the command keys that the config file read into v
+// sets, unless the file is trusted or the environment overrides them, and
+// returns their names.
+func ignoreCommands(opts Options, v *viper.Viper) []string {
+	file := v.ConfigFileUsed()
+	if file == "" || Trusted(opts, file) {
+		return nil
+	}
+	var ignored []string
+	for _, k := range Keys {
+		if k.Command && os.Getenv(k.Env) == "" && v.InConfig(k.Name) {
+			v.Set(k.Name, "")
+			ignored = append(ignored, k.Name)
+		}
+	}
+	return ignored
+}
+
+// Trusted reports whether the config file at path may set command keys
+// (api_key_command, pager): it was named by opts.File or MANUALS_CONFIG,
+// or is one of the user's own, in the home or config directory. A
+// ./.manuals.yaml found in the working directory may have come with a
+// checked-out project, so it is not trusted to run commands.
+func Trusted(opts Options, path string) bool {
+	if opts.File != "" || os.Getenv(ConfigEnv) != "" {
+		return true
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if home, err := os.UserHomeDir(); err == nil && filepath.Dir(abs) == home &&
+		slices.Contains(exts, filepath.Ext(abs)) && strings.TrimSuffix(filepath.Base(abs), filepath.Ext(abs)) == ".manuals" {
+		return true
+	}
+	dir, err := Dir()
+	return err == nil && filepath.Dir(abs) == filepath.Clean(dir)
+}
+
+// readFile reads the config file named by file, or found by FindFile if
+// file is empty. A missing file is only an error if it was named
+// explicitly.
+func readFile(file string) (*viper.Viper, error) {
+	v := newViper()
+
+	explicit := file != "" || os.Getenv(ConfigEnv) != ""
+	if file == "" {
+		file = FindFile()
+	}
+	if file == "" {
+		return v, nil
+	}
+
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return newViper(), nil
+		}
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	return v, nil
+}
+
+// exts are the config file extensions FindFile looks for, in order.
+var exts = []string{".yaml", ".yml", ".json"}
+
+// FindFile returns the config file to use: MANUALS_CONFIG if set, else
+// the first that exists of ~/.manuals.yaml, ./.manuals.yaml,
+// $XDG_CONFIG_HOME/manuals/config.yaml and
+// $XDG_CONFIG_HOME/manuals/.manuals.yaml, each also tried with the .yml
+// and .json extensions. It returns "" if none exists.
+func FindFile() string {
+	if file := os.Getenv(ConfigEnv); file != "" {
+		return file
+	}
+
+	var bases []string
+	if home, err := os.UserHomeDir(); err == nil {
+		bases = append(bases, filepath.Join(home, ".manuals"))
+	}
+	bases = append(bases, ".manuals")
+	if dir, err := Dir(); err == nil {
+		bases = append(bases,
+			filepath.Join(dir, "config"),
+			filepath.Join(dir, ".manuals"),
+		)
+	}
+
+	var candidates []string
+	for _, base := range bases {
+		for _, ext := range exts {
+			candidates = append(candidates, base+ext)
+		}
+	}
+
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			return c
+		}
+	}
+	return ""
+}
+
+// Dir returns the XDG config directory for manuals.
+func Dir() (string, error) {
+	return xdg.ConfigDir()
+}
+
+// DefaultFile returns the path `manuals config init` writes to.
+func DefaultFile() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// newViper returns a viper instance with defaults and environment
+// bindings set up.
+func newViper() *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	// Environment variables
+	v.SetEnvPrefix("MANUALS")
+	v.AutomaticEnv()
+
+	for _, k := range Keys {
+		if k.Default != "" {
+			v.SetDefault(k.Name, k.Default)
+		}
+		_ = v.BindEnv(k.Name, k.Env)
+	}
+
+	return v
+}
+
+// CredentialsFile returns the path of the encrypted credentials file.
+func CredentialsFile() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.enc"), nil
+}
+
 // Validate checks that required configuration is present.
 func (c *Config) Validate() error {
 	if c.APIKey == "" {
-		return fmt.Errorf("API key required: set MANUALS_API_KEY or add api_key to config file")
+		return fmt.Errorf("API key required: run 'manuals auth login', set MANUALS_API_KEY, or add api_key or api_key_command to config file")
 	}
 	return nil
 }
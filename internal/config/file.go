@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// setFileValue sets a dotted key (e.g. "profiles.staging.api_url") in the
+// YAML config file at path, creating intermediate mappings as needed.
+// Comments and the order of existing keys are preserved. JSON config files
+// are not edited.
+func setFileValue(path, key string, value interface{}) error {
+	if filepath.Ext(path) == ".json" {
+		return fmt.Errorf("%s is a JSON file; only YAML config files can be edited (set %s by hand)", path, key)
+	}
+	doc, err := readYAML(path)
+	if err != nil {
+		return err
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: %s is not a mapping", path, strings.Join(parts[:i], "."))
+		}
+
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == part {
+				child = node.Content[j+1]
+				break
+			}
+		}
+
+		last := i == len(parts)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = &valueNode
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part},
+				child,
+			)
+		} else if last {
+			// Keep any comments attached to the old value.
+			valueNode.HeadComment = child.HeadComment
+			valueNode.LineComment = child.LineComment
+			valueNode.FootComment = child.FootComment
+			*child = valueNode
+		}
+		node = child
+	}
+
+	return writeYAML(path, doc)
+}
+
+// readYAML parses the YAML file at path, returning an empty document if
+// it does not exist or is empty.
+func readYAML(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	return &doc, nil
+}
+
+// writeYAML writes a YAML document to path, keeping it private to the
+// user since it may contain an API key.
+func writeYAML(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
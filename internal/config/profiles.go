@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Profile is a named set of settings in the config file.
+type Profile struct {
+	Name    string `json:"name"`
+	APIURL  string `json:"api_url,omitempty"`
+	Current bool   `json:"current"`
+}
+
+// Profiles returns the profiles defined in the config file (see
+// Options.File), sorted by name, along with the path of the file.
+func Profiles(file string) ([]Profile, string, error) {
+	v, err := readFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	current := v.GetString("current_profile")
+	var profiles []Profile
+	for name := range v.GetStringMap("profiles") {
+		profiles = append(profiles, Profile{
+			Name:    name,
+			APIURL:  v.GetString("profiles." + name + ".api_url"),
+			Current: name == current,
+		})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, v.ConfigFileUsed(), nil
+}
+
+// UseProfile makes name the current profile in the config file and
+// returns the path of the file.
+func UseProfile(file, name string) (string, error) {
+	v, err := readFile(file)
+	if err != nil {
+		return "", err
+	}
+	if v.ConfigFileUsed() == "" || !v.IsSet("profiles."+name) {
+		return "", fmt.Errorf("profile %q not found in config", name)
+	}
+
+	path := v.ConfigFileUsed()
+	if err := setFileValue(path, "current_profile", name); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting is the effective value of a configuration key and where it came
+// from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Redacted returns the value, masked if the key is secret.
+func (s Setting) Redacted() string {
+	if k, ok := LookupKey(s.Key); ok && k.Secret {
+		return Redact(s.Value)
+	}
+	return s.Value
+}
+
+// Redact masks a secret, keeping only its last four characters when it is
+// long enough for that to be safe.
+func Redact(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) < 12:
+		return "********"
+	default:
+		return "********" + secret[len(secret)-4:]
+	}
+}
+
+// Settings resolves every known key, reporting for each its effective
+// value and its source: an environment variable, the active profile, the
+// config file, or the built-in default. Command keys that an untrusted
+// file sets are reported as ignored.
+func Settings(opts Options) ([]Setting, error) {
+	v, profile, err := load(opts)
+	if err != nil {
+		return nil, err
+	}
+	file := v.ConfigFileUsed()
+	ignored := ignoreCommands(opts, v)
+
+	// A separate instance tells file values from profile values, which
+	// load has merged.
+	raw, err := readFile(opts.File)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]Setting, 0, len(Keys))
+	for _, k := range Keys {
+		s := Setting{Key: k.Name, Value: v.GetString(k.Name)}
+		switch {
+		case os.Getenv(k.Env) != "":
+			s.Source = "env " + k.Env
+		case slices.Contains(ignored, k.Name):
+			s.Source = "ignored in untrusted file " + file
+		case profile != "" && raw.InConfig("profiles."+profile+"."+k.Name):
+			s.Source = fmt.Sprintf("profile %s (%s)", profile, file)
+		case raw.InConfig(k.Name):
+			s.Source = "file " + file
+		case k.Default != "":
+			s.Source = "default"
+		default:
+			s.Source = "unset"
+		}
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+// Set writes key to the config file at path, under profiles.<profile> if
+// profile is non-empty. The value is validated against the key's type.
+func Set(path, profile, key, value string) error {
+	k, ok := LookupKey(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	var typed interface{} = value
+	choice, enumerated := value, len(k.Values) > 0
+	switch k.Name {
+	case "retries", "snippet_length":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a non-negative integer", key)
+		}
+		typed = n
+	case "timeout", "retry_wait", "retry_max_wait", "cache_ttl":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s must be a duration such as 30s or 5m: %w", key, err)
+		}
+	case "output_format":
+		// Format names are case-insensitive, and templates and JSONPath
+		// expressions take an argument.
+		choice = strings.ToLower(value)
+		if name, _, ok := strings.Cut(value, "="); ok && slices.Contains([]string{"template", "template-file", "jsonpath"}, name) {
+			enumerated = false
+		}
+	}
+	if enumerated && !slices.Contains(k.Values, choice) {
+		return fmt.Errorf("%s must be one of %s", key, strings.Join(k.Values, ", "))
+	}
+
+	return setFileValue(path, profileKey(profile, key), typed)
+}
+
+// profileKey returns the path of key in the config file: under
+// profiles.<profile> if profile is non-empty.
+func profileKey(profile, key string) string {
+	if profile == "" {
+		return key
+	}
+	return "profiles." + profile + "." + key
+}
+
+// Starter returns a commented starter config file. apiURL and apiKey, if
+// non-empty, replace the defaults.
+func Starter(apiURL, apiKey string) []byte {
+	var b strings.Builder
+	b.WriteString(`# manuals CLI configuration
+#
+# Environment variables (MANUALS_*) and command-line flags override the
+# values in this file. Run 'manuals config path' to see where each
+# effective value comes from.
+`)
+
+	for _, k := range Keys {
+		value := k.Default
+		switch k.Name {
+		case "api_url":
+			if apiURL != "" {
+				value = apiURL
+			}
+		case "api_key":
+			value = apiKey
+		}
+
+		fmt.Fprintf(&b, "\n# %s (env: %s)\n", capitalize(k.Description), k.Env)
+		switch {
+		case k.Secret:
+			fmt.Fprintf(&b, "%s: %s\n", k.Name, strconv.Quote(value))
+		case value != "":
+			fmt.Fprintf(&b, "%s: %s\n", k.Name, value)
+		default:
+			fmt.Fprintf(&b, "# %s:\n", k.Name)
+		}
+	}
+
+	b.WriteString(`
+# Named profiles override the settings above. Select one with --profile,
+# MANUALS_PROFILE, or 'manuals config use-profile <name>'.
+# current_profile: staging
+# profiles:
+#   staging:
+#     api_url: https://manuals-staging.example.com
+#     api_key: "staging-key"
+#     output_format: json
+`)
+
+	return []byte(b.String())
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
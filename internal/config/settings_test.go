@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const commented = `# manuals CLI configuration
+
+# base URL of the Manuals API
+api_url: http://localhost:8080 # local server
+timeout: 30s
+profiles:
+  # production server
+  prod:
+    api_url: https://manuals.example.com
+current_profile: prod
+`
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name         string
+		profile, key string
+		value        string
+		want         string // the config file after Set
+	}{
+		{
+			name: "replace", key: "api_url", value: "http://nas:8080",
+			want: strings.Replace(commented, "http://localhost:8080", "http://nas:8080", 1),
+		},
+		{
+			name: "add", key: "retries", value: "5",
+			want: commented + "retries: 5\n",
+		},
+		{
+			name: "duration", key: "timeout", value: "2m",
+			want: strings.Replace(commented, "30s", "2m", 1),
+		},
+		{
+			name: "profile", profile: "prod", key: "api_url", value: "https://manuals.example.org",
+			want: strings.Replace(commented, "manuals.example.com", "manuals.example.org", 1),
+		},
+		{
+			name: "new profile key", profile: "prod", key: "api_key", value: "secret",
+			want: strings.Replace(commented, "manuals.example.com\n", "manuals.example.com\n    api_key: secret\n", 1),
+		},
+		{
+			name: "new profile", profile: "staging", key: "color", value: "never",
+			want: strings.Replace(commented, "current_profile", "  staging:\n    color: never\ncurrent_profile", 1),
+		},
+		{
+			name: "output format", key: "output_format", value: "template={{.Name}}",
+			want: commented + "output_format: template={{.Name}}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(commented), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := Set(path, tt.profile, tt.key, tt.value); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("config file:\n%s\nwant:\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := Set(path, "", "api_url", "http://nas:8080"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "api_url: http://nas:8080\n" {
+		t.Errorf("config file = %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode %o, want 600", perm)
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		key, value string
+		wantErr    string
+	}{
+		{"nope", "1", `unknown config key "nope"`},
+		{"retries", "many", "retries must be a non-negative integer"},
+		{"retries", "-1", "retries must be a non-negative integer"},
+		{"timeout", "30", "timeout must be a duration"},
+		{"color", "sometimes", "color must be one of auto, always, never"},
+		{"output_format", "xml", "output_format must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(commented), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := Set(path, "", tt.key, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Set error = %v, want %q", err, tt.wantErr)
+			}
+			if data, _ := os.ReadFile(path); string(data) != commented {
+				t.Errorf("a failed Set changed the file:\n%s", data)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"api_url": "http://localhost:8080"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "", "api_url", "http://nas:8080"); err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Errorf("Set of a JSON file: %v, want a refusal", err)
+	}
+}
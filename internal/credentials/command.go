@@ -0,0 +1,46 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds how long a credential helper may run.
+const commandTimeout = 30 * time.Second
+
+// RunCommand runs a credential helper command line (e.g. "pass show
+// manuals") through the shell and returns the first line of its output
+// as the API key. The helper's stderr is passed through so it can prompt.
+func RunCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("api_key_command failed: %w", err)
+	}
+
+	key, _, _ := strings.Cut(stdout.String(), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("api_key_command printed no API key")
+	}
+	return key, nil
+}
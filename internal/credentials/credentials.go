@@ -0,0 +1,61 @@
+// Package credentials stores API keys outside the config file, in the
+// desktop keyring (Secret Service) or an encrypted file, and runs
+// credential helper commands.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Service is the service name under which keys are stored.
+const Service = "manuals"
+
+// ErrNotFound is returned when no key is stored for an account.
+var ErrNotFound = errors.New("no stored API key")
+
+// Store persists API keys, one per account. Accounts are API base URLs,
+// so each server (and thus each profile) has its own key.
+type Store interface {
+	// Name describes the store for display.
+	Name() string
+
+	// Get returns the key for account, or ErrNotFound.
+	Get(account string) (string, error)
+
+	// Set stores the key for account, replacing any existing key.
+	Set(account, secret string) error
+
+	// Delete removes the key for account. Deleting a missing key is not
+	// an error.
+	Delete(account string) error
+}
+
+// Store kinds accepted by Open.
+const (
+	KindAuto          = "auto"
+	KindSecretService = "secret-service"
+	KindFile          = "file"
+)
+
+// Open returns the store of the given kind. KindAuto (or "") selects the
+// Secret Service keyring when it is available and the encrypted file at
+// filePath otherwise.
+func Open(kind, filePath string) (Store, error) {
+	switch kind {
+	case "", KindAuto:
+		if SecretServiceAvailable() {
+			return &secretService{}, nil
+		}
+		return NewFileStore(filePath), nil
+	case KindSecretService:
+		if !SecretServiceAvailable() {
+			return nil, errors.New("secret service unavailable: install secret-tool (libsecret) and run within a desktop session")
+		}
+		return &secretService{}, nil
+	case KindFile:
+		return NewFileStore(filePath), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (use auto, secret-service or file)", kind)
+	}
+}
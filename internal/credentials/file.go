@@ -0,0 +1,171 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase that
+// protects the encrypted credentials file.
+const PassphraseEnv = "MANUALS_KEYRING_PASSPHRASE"
+
+// pbkdf2Iterations is the PBKDF2-SHA256 work factor.
+const pbkdf2Iterations = 600_000
+
+// FileStore keeps keys in a file encrypted with AES-256-GCM, using a key
+// derived from a passphrase (MANUALS_KEYRING_PASSPHRASE) with PBKDF2. It
+// is meant for headless machines without a desktop keyring.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a store backed by the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// encryptedFile is the on-disk format.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Name describes the store by its file.
+func (s *FileStore) Name() string {
+	return "encrypted file " + s.path
+}
+
+// Get returns the key for account, or ErrNotFound.
+func (s *FileStore) Get(account string) (string, error) {
+	keys, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := keys[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores the key for account, re-encrypting the file.
+func (s *FileStore) Set(account, secret string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	keys[account] = secret
+	return s.save(keys)
+}
+
+// Delete removes the key for account. Deleting a missing key is not an
+// error and leaves the file untouched.
+func (s *FileStore) Delete(account string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[account]; !ok {
+		return nil
+	}
+	delete(keys, account)
+	return s.save(keys)
+}
+
+// load decrypts the stored keys. A missing file holds no keys.
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+
+	gcm, err := s.cipher(f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong %s?", s.path, PassphraseEnv)
+	}
+
+	keys := map[string]string{}
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return keys, nil
+}
+
+// save encrypts keys with a fresh salt and nonce and writes them.
+func (s *FileStore) save(keys map[string]string) error {
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	f := encryptedFile{
+		Version: 1,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := s.cipher(f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plain, nil)
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// cipher derives the AES-GCM cipher for salt from the passphrase.
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("set %s to use the encrypted credentials file", PassphraseEnv)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,87 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	path := filepath.Join(t.TempDir(), "sub", "credentials.json")
+	s := NewFileStore(path)
+
+	if _, err := s.Get("https://a.example"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get from a missing file: %v, want ErrNotFound", err)
+	}
+	if err := s.Set("https://a.example", "key-a"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Set("https://b.example", "key-b"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A new store reads what the first wrote.
+	s = NewFileStore(path)
+	for account, want := range map[string]string{"https://a.example": "key-a", "https://b.example": "key-b"} {
+		if got, err := s.Get(account); err != nil || got != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", account, got, err, want)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode %o, want 600", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("key-a")) {
+		t.Error("the file holds a key in plain text")
+	}
+
+	if err := s.Delete("https://a.example"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get("https://a.example"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if got, err := s.Get("https://b.example"); err != nil || got != "key-b" {
+		t.Errorf("Get of the other key after Delete = %q, %v", got, err)
+	}
+
+	// Deleting a missing key leaves the file untouched.
+	before, _ := os.ReadFile(path)
+	if err := s.Delete("https://missing.example"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("deleting a missing key rewrote the file")
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := NewFileStore(path).Set("https://a.example", "key-a"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "battery staple")
+	_, err := NewFileStore(path).Get("https://a.example")
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Get with the wrong passphrase: %v, want a decryption error", err)
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	if _, err := NewFileStore(path).Get("https://a.example"); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("Get without a passphrase: %v, want an error naming %s", err, PassphraseEnv)
+	}
+}
@@ -0,0 +1,80 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// secretService stores keys in the freedesktop Secret Service (GNOME
+// Keyring, KWallet) through the secret-tool command from libsecret.
+type secretService struct{}
+
+// SecretServiceAvailable reports whether secret-tool is installed and a
+// session bus is available to reach the keyring.
+func SecretServiceAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+// Name describes the store.
+func (s *secretService) Name() string {
+	return "secret service keyring"
+}
+
+// Get looks up the key for account with secret-tool, returning
+// ErrNotFound if the keyring has none.
+func (s *secretService) Get(account string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", Service, "account", account)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits 1 without output when nothing matches.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret-tool lookup: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	secret := strings.TrimRight(stdout.String(), "\r\n")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores the key for account in the keyring, replacing any existing
+// one.
+func (s *secretService) Set(account, secret string) error {
+	// The secret is passed on stdin so it never appears in the process list.
+	cmd := exec.Command("secret-tool", "store",
+		"--label", "Manuals API key ("+account+")",
+		"service", Service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Delete removes the key for account. Deleting a missing key is not an
+// error.
+func (s *secretService) Delete(account string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", Service, "account", account)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(output)) == 0 {
+			return nil
+		}
+		return fmt.Errorf("secret-tool clear: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package index implements a small full-text index with BM25 ranking, used
+// to search a local catalog mirror without the server.
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Field weights: a term in a device name counts as much as this many
+// occurrences in its content.
+const (
+	nameWeight     = 3
+	metadataWeight = 2
+)
+
+// Doc is an indexed device.
+type Doc struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Length int    `json:"length"`
+}
+
+// Posting records how often a term occurs in a document.
+type Posting struct {
+	Doc  int `json:"d"`
+	Freq int `json:"f"`
+}
+
+// Index is an inverted index over devices.
+type Index struct {
+	Docs     []Doc                `json:"docs"`
+	Postings map[string][]Posting `json:"postings"`
+	TotalLen int                  `json:"total_len"`
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{Postings: map[string][]Posting{}}
+}
+
+// Add indexes a device's name, type, domain, metadata and content.
+func (ix *Index) Add(d *client.Device) {
+	freqs := map[string]int{}
+	length := 0
+	add := func(text string, weight int) {
+		for _, t := range Tokenize(text) {
+			freqs[t] += weight
+			length += weight
+		}
+	}
+
+	add(d.Name, nameWeight)
+	add(d.Domain+" "+d.Type, metadataWeight)
+	for k, v := range d.Metadata {
+		add(k+" "+fmt.Sprint(v), metadataWeight)
+	}
+	add(d.Content, 1)
+
+	doc := len(ix.Docs)
+	ix.Docs = append(ix.Docs, Doc{
+		ID:     d.ID,
+		Name:   d.Name,
+		Domain: d.Domain,
+		Type:   d.Type,
+		Path:   d.Path,
+		Length: length,
+	})
+	ix.TotalLen += length
+
+	for t, f := range freqs {
+		ix.Postings[t] = append(ix.Postings[t], Posting{Doc: doc, Freq: f})
+	}
+}
+
+// Hit is a search match.
+type Hit struct {
+	Doc   Doc
+	Score float64
+}
+
+// Search ranks indexed devices against query using BM25 and returns at
+// most limit hits, best first. Scores are BM25 scores s mapped to
+// s/(s+1), which keeps them between 0 and 1 without making them depend
+// on the other hits, so they can be compared against a fixed threshold.
+func (ix *Index) Search(query string, limit int) []Hit {
+	if len(ix.Docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(ix.Docs))
+	avgLen := float64(ix.TotalLen) / n
+	scores := map[int]float64{}
+
+	seen := map[string]bool{}
+	for _, t := range Tokenize(query) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		postings := ix.Postings[t]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.Freq)
+			norm := 1 - b + b*float64(ix.Docs[p.Doc].Length)/avgLen
+			scores[p.Doc] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for doc, score := range scores {
+		hits = append(hits, Hit{Doc: ix.Docs[doc], Score: score / (score + 1)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Doc.Name < hits[j].Doc.Name
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// Load reads an index from path.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ix := New()
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	return ix, nil
+}
+
+// Save writes the index to path atomically.
+func (ix *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Tokenize splits text into lowercase terms, dropping single characters
+// (other than digits) and common stop words.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := fields[:0]
+	for _, f := range fields {
+		if stopWords[f] {
+			continue
+		}
+		if len([]rune(f)) == 1 && !unicode.IsDigit([]rune(f)[0]) {
+			continue
+		}
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+var stopWords = map[string]bool{
+	"an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "with": true,
+}
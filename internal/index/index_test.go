@@ -0,0 +1,112 @@
+package index
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+)
+
+func testIndex() *Index {
+	ix := New()
+	for _, d := range []*client.Device{
+		{ID: "esp32", Name: "ESP32 DevKit", Domain: "hardware", Type: "mcu", Content: "Dual core WiFi and Bluetooth microcontroller."},
+		{ID: "bme280", Name: "BME280", Domain: "hardware", Type: "sensor", Content: "Temperature, humidity and pressure sensor with I2C."},
+		{ID: "sht31", Name: "SHT31", Domain: "hardware", Type: "sensor", Content: "Temperature and humidity sensor. The temperature accuracy is 0.2 degrees."},
+		{ID: "gimp", Name: "GIMP", Domain: "software", Type: "app", Content: "Image editor.", Metadata: map[string]interface{}{"license": "GPL"}},
+	} {
+		ix.Add(d)
+	}
+	return ix
+}
+
+func ids(hits []Hit) []string {
+	var out []string
+	for _, h := range hits {
+		out = append(out, h.Doc.ID)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex()
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		// A name match outranks the same term in content.
+		{"esp32", 0, []string{"esp32"}},
+		{"bluetooth", 0, []string{"esp32"}},
+		// Repeated terms rank higher; ties are broken by name.
+		{"temperature", 0, []string{"sht31", "bme280"}},
+		{"sensor", 0, []string{"bme280", "sht31"}},
+		// Documents matching more query terms rank higher.
+		{"pressure sensor", 0, []string{"bme280", "sht31"}},
+		{"sensor", 1, []string{"bme280"}},
+		{"gpl", 0, []string{"gimp"}},
+		{"The Sensor", 0, []string{"bme280", "sht31"}},
+		{"zigbee", 0, nil},
+		{"the", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			hits := ix.Search(tt.query, tt.limit)
+			if got := ids(hits); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for _, h := range hits {
+				if h.Score <= 0 || h.Score >= 1 {
+					t.Errorf("score of %s = %v, want between 0 and 1", h.Doc.ID, h.Score)
+				}
+			}
+		})
+	}
+
+	if hits := New().Search("sensor", 0); hits != nil {
+		t.Errorf("Search of an empty index = %v", hits)
+	}
+}
+
+func TestSearchScoreIndependent(t *testing.T) {
+	// Scores are not normalized against the best hit, so a lone weak
+	// match does not score 1.
+	hits := testIndex().Search("bluetooth", 0)
+	if len(hits) != 1 || hits[0].Score >= 0.9 {
+		t.Fatalf("Search(bluetooth) = %+v, want one weak hit", hits)
+	}
+
+	// A document's score does not change when the query also matches
+	// others.
+	ix := testIndex()
+	alone := ix.Search("pressure", 0)
+	both := ix.Search("pressure zigbee", 0)
+	if len(alone) != 1 || len(both) != 1 || alone[0].Score != both[0].Score {
+		t.Errorf("scores %+v and %+v differ", alone, both)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index", "index.json")
+	ix := testIndex()
+	if err := ix.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want, got := ix.Search("temperature sensor", 0), loaded.Search("temperature sensor", 0)
+	if !slices.Equal(ids(got), ids(want)) || got[0].Score != want[0].Score {
+		t.Errorf("loaded index returned %+v, want %+v", got, want)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("The ESP32-S3 is a 2.4 GHz SoC, with Wi-Fi & BLE.")
+	want := []string{"esp32", "s3", "2", "4", "ghz", "soc", "wi", "fi", "ble"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
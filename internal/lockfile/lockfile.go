@@ -0,0 +1,190 @@
+// Package lockfile reads and writes manuals.lock files, which pin the
+// documents a project depends on by ID and checksum.
+package lockfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"go.yaml.in/yaml/v3"
+)
+
+// Name is the file name of lockfiles.
+const Name = "manuals.lock"
+
+// DefaultDir is the directory, relative to the lockfile, that documents
+// are installed into when the lockfile does not name one.
+const DefaultDir = "docs"
+
+// Version is the lockfile format version written by this package.
+const Version = 1
+
+// ErrNotFound is returned by Find when there is no lockfile.
+var ErrNotFound = errors.New("no " + Name + " in this directory or its parents (run 'manuals add' to create one)")
+
+// header is written at the top of every lockfile.
+const header = `# Documents pinned by this project. Managed by 'manuals add';
+# 'manuals install' downloads them and 'manuals outdated' checks for changes.
+`
+
+// File is a lockfile.
+type File struct {
+	// Version is the format version.
+	Version int `yaml:"version" json:"version"`
+
+	// Dir is the install directory, relative to the lockfile. It must be
+	// local to the project: Load rejects absolute paths and paths that
+	// leave the lockfile's directory.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Documents are the pinned documents, in the order they were added.
+	Documents []Entry `yaml:"documents" json:"documents"`
+
+	path string
+}
+
+// Entry pins a document.
+type Entry struct {
+	ID        string `yaml:"id" json:"id"`
+	Filename  string `yaml:"filename" json:"filename"`
+	DeviceID  string `yaml:"device_id" json:"device_id"`
+	Device    string `yaml:"device,omitempty" json:"device,omitempty"`
+	Checksum  string `yaml:"checksum" json:"checksum"`
+	SizeBytes int64  `yaml:"size_bytes" json:"size_bytes"`
+	IndexedAt string `yaml:"indexed_at" json:"indexed_at"`
+}
+
+// NewEntry returns the entry pinning doc, a document of the named device.
+func NewEntry(doc *client.Document, device string) Entry {
+	return Entry{
+		ID:        doc.ID,
+		Filename:  doc.Filename,
+		DeviceID:  doc.DeviceID,
+		Device:    device,
+		Checksum:  doc.Checksum,
+		SizeBytes: doc.SizeBytes,
+		IndexedAt: doc.IndexedAt,
+	}
+}
+
+// Document returns the document as pinned by e.
+func (e Entry) Document() *client.Document {
+	return &client.Document{
+		ID:        e.ID,
+		DeviceID:  e.DeviceID,
+		Filename:  e.Filename,
+		SizeBytes: e.SizeBytes,
+		Checksum:  e.Checksum,
+		IndexedAt: e.IndexedAt,
+	}
+}
+
+// New returns an empty lockfile to be saved at path.
+func New(path string) *File {
+	return &File{Version: Version, path: path}
+}
+
+// Find returns the path of the lockfile in dir or its nearest parent that
+// has one, or ErrNotFound.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, Name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotFound
+		}
+		dir = parent
+	}
+}
+
+// Load reads the lockfile at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{path: path}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Version > Version {
+		return nil, fmt.Errorf("%s: unsupported lockfile version %d (upgrade manuals)", path, f.Version)
+	}
+	if f.Dir != "" && !filepath.IsLocal(f.Dir) {
+		return nil, fmt.Errorf("%s: dir %q must be a relative path inside the project (use --dir to install elsewhere)", path, f.Dir)
+	}
+	return f, nil
+}
+
+// Path returns the path of the lockfile.
+func (f *File) Path() string {
+	return f.path
+}
+
+// InstallDir returns the directory documents are installed into.
+func (f *File) InstallDir() string {
+	dir := f.Dir
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return filepath.Join(filepath.Dir(f.path), dir)
+}
+
+// Lookup returns the entry for the document with the given ID.
+func (f *File) Lookup(id string) (*Entry, bool) {
+	for i := range f.Documents {
+		if f.Documents[i].ID == id {
+			return &f.Documents[i], true
+		}
+	}
+	return nil, false
+}
+
+// Put adds e, or replaces the entry with the same ID. It reports whether an
+// existing entry was changed.
+func (f *File) Put(e Entry) (updated bool) {
+	if old, ok := f.Lookup(e.ID); ok {
+		updated = *old != e
+		*old = e
+		return updated
+	}
+	f.Documents = append(f.Documents, e)
+	return false
+}
+
+// Save writes the lockfile, replacing it atomically.
+func (f *File) Save() error {
+	if f.Version == 0 {
+		f.Version = Version
+	}
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
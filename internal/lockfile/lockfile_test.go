@@ -0,0 +1,130 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, Name)
+	f := New(path)
+	f.Dir = "vendor/manuals"
+	doc := &client.Document{ID: "d1", DeviceID: "dev1", Filename: "esp32.pdf", Checksum: "sha256:abc", SizeBytes: 1024, IndexedAt: "2026-01-02T03:04:05Z"}
+	f.Put(NewEntry(doc, "ESP32"))
+	f.Put(Entry{ID: "d2", Filename: "bme280.pdf", DeviceID: "dev2", Checksum: "sha256:def"})
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), header) {
+		t.Errorf("lockfile does not start with the header:\n%s", data)
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Path() != path || loaded.Version != Version || !reflect.DeepEqual(loaded.Documents, f.Documents) {
+		t.Errorf("loaded %+v, want %+v", loaded, f)
+	}
+	if got, want := loaded.InstallDir(), filepath.Join(dir, "vendor", "manuals"); got != want {
+		t.Errorf("InstallDir = %s, want %s", got, want)
+	}
+	e, ok := loaded.Lookup("d1")
+	if !ok || !reflect.DeepEqual(e.Document(), doc) || e.Device != "ESP32" {
+		t.Errorf("Lookup(d1) = %+v, %v", e, ok)
+	}
+}
+
+func TestPut(t *testing.T) {
+	f := New(Name)
+	a := Entry{ID: "d1", Filename: "a.pdf", Checksum: "sha256:1"}
+	b := Entry{ID: "d2", Filename: "b.pdf", Checksum: "sha256:2"}
+	if f.Put(a) || f.Put(b) {
+		t.Error("Put of a new entry reported an update")
+	}
+	if f.Put(a) {
+		t.Error("Put of an unchanged entry reported an update")
+	}
+	a.Checksum = "sha256:3"
+	if !f.Put(a) {
+		t.Error("Put of a changed entry reported no update")
+	}
+	if want := []Entry{a, b}; !reflect.DeepEqual(f.Documents, want) {
+		t.Errorf("Documents = %+v, want %+v in order", f.Documents, want)
+	}
+	if _, ok := f.Lookup("d3"); ok {
+		t.Error("Lookup of a missing ID succeeded")
+	}
+	if got := f.InstallDir(); got != DefaultDir {
+		t.Errorf("InstallDir = %s, want %s", got, DefaultDir)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"newer version", "version: 2\ndocuments: []\n", "unsupported lockfile version 2"},
+		{"absolute dir", "version: 1\ndir: /etc\n", `dir "/etc" must be a relative path`},
+		{"parent dir", "version: 1\ndir: ../docs\n", `dir "../docs" must be a relative path`},
+		{"escaping dir", "version: 1\ndir: docs/../../x\n", "must be a relative path"},
+		{"invalid", "version: [\n", Name},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), Name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), Name)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load of a missing file: %v, want ErrNotExist", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Find(sub); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find without a lockfile: %v, want ErrNotFound", err)
+	}
+
+	want := filepath.Join(root, "a", Name)
+	if err := New(want).Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{sub, filepath.Join(root, "a")} {
+		if got, err := Find(dir); err != nil || got != want {
+			t.Errorf("Find(%s) = %s, %v; want %s", dir, got, err, want)
+		}
+	}
+	if _, err := Find(root); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find above the lockfile: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,444 @@
+// Package mcp implements a Model Context Protocol server over stdio, which
+// exchanges newline-delimited JSON-RPC 2.0 messages on stdin and stdout.
+//
+// Only the server side of tools and resources is implemented: clients can
+// list and call tools, and list and read resources by template. Requests
+// are handled one at a time, in order.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ProtocolVersion is the latest protocol version supported.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the protocol versions the server can speak. A
+// client asking for another gets ProtocolVersion and decides whether to
+// continue.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeResourceNotFound = -32002
+)
+
+// Error is a JSON-RPC error. Resource readers may return one to choose the
+// error code; other errors are reported as internal errors.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Tool is a tool the client can call.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// InputSchema is the JSON Schema of the tool's arguments, an object.
+	InputSchema json.RawMessage `json:"inputSchema"`
+
+	// Handler runs the tool with the arguments given by the client, which
+	// are empty if there are none. An error is returned to the client as
+	// the tool's result, so the model can see it and try again.
+	Handler func(ctx context.Context, args json.RawMessage) (*ToolResult, error) `json:"-"`
+}
+
+// Content is a content block of a tool result.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ToolResult is the result of a tool call.
+type ToolResult struct {
+	Content []Content `json:"content"`
+
+	// StructuredContent is the result as a JSON object, for clients that
+	// use it; Content holds the same as text.
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+
+	IsError bool `json:"isError,omitempty"`
+}
+
+// TextResult returns a tool result of plain text.
+func TextResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// JSONResult returns a tool result holding v, which must encode as a JSON
+// object, both as text and as structured content.
+func JSONResult(v interface{}) (*ToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	res := TextResult(string(data))
+	res.StructuredContent = v
+	return res, nil
+}
+
+// ResourceTemplate describes resources the client can read by URI.
+type ResourceTemplate struct {
+	// URITemplate is the URI of the resources, with variables in braces,
+	// e.g. "manuals://devices/{id}/content". A variable matches a
+	// non-empty path segment.
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+
+	// Read returns the resource at uri, given the values of the template
+	// variables, unescaped.
+	Read func(ctx context.Context, uri string, vars map[string]string) (*ResourceContents, error) `json:"-"`
+
+	pattern *regexp.Regexp
+	names   []string
+}
+
+// ResourceContents is the text of a resource.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// templateVar matches variables in URI templates.
+var templateVar = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// match reports whether uri matches the template, and returns the values
+// of its variables.
+func (t *ResourceTemplate) match(uri string) (map[string]string, bool) {
+	m := t.pattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+	vars := make(map[string]string, len(t.names))
+	for i, name := range t.names {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = v
+	}
+	return vars, true
+}
+
+// Server is an MCP server.
+type Server struct {
+	name, version string
+
+	// Instructions tell the model how to use the server.
+	Instructions string
+
+	tools     []Tool
+	templates []ResourceTemplate
+}
+
+// NewServer returns a server that identifies itself by name and version.
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version}
+}
+
+// AddTool adds a tool.
+func (s *Server) AddTool(t Tool) {
+	s.tools = append(s.tools, t)
+}
+
+// AddResourceTemplate adds a resource template.
+func (s *Server) AddResourceTemplate(t ResourceTemplate) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, m := range templateVar.FindAllStringSubmatchIndex(t.URITemplate, -1) {
+		pattern.WriteString(regexp.QuoteMeta(t.URITemplate[last:m[0]]))
+		pattern.WriteString("([^/]+)")
+		t.names = append(t.names, t.URITemplate[m[2]:m[3]])
+		last = m[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(t.URITemplate[last:]) + "$")
+	t.pattern = regexp.MustCompile(pattern.String())
+	s.templates = append(s.templates, t)
+}
+
+// request is a JSON-RPC request, or a notification if it has no ID.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// maxMessage bounds the size of a message read from the client.
+const maxMessage = 16 << 20
+
+// message is a line read from the client. tooLong reports a line longer
+// than maxMessage, which was discarded.
+type message struct {
+	line    []byte
+	tooLong bool
+}
+
+// Serve reads messages from r and writes responses to w until r is
+// exhausted or ctx is done. Only failures to read or write are returned;
+// errors handling a message, including messages over 16 MiB, are sent to
+// the client.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan message)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		br := bufio.NewReaderSize(r, 64*1024)
+		for {
+			line, tooLong, err := readLine(br, maxMessage)
+			msg := message{line: bytes.TrimSpace(line), tooLong: tooLong}
+			if msg.tooLong || len(msg.line) > 0 {
+				select {
+				case lines <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					readErr <- err
+				}
+				return
+			}
+		}
+	}()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			if line.tooLong {
+				resp := &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: fmt.Sprintf("message exceeds %d bytes", maxMessage)}}
+				if err := enc.Encode(resp); err != nil {
+					return err
+				}
+				continue
+			}
+			if resp := s.handle(ctx, line.line); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// readLine reads a line from br, including its newline. A line longer
+// than max bytes is read to its end but not returned, and tooLong is set.
+func readLine(br *bufio.Reader, max int) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > max+1 {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// handle handles a message and returns the response, or nil for
+// notifications.
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	if line[0] == '[' {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeInvalidRequest, Message: "batches are not supported"}}
+	}
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}}
+	}
+	if len(req.ID) == 0 {
+		// Notifications (initialized, cancelled, ...) need no answer.
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}
+		return resp
+	}
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// dispatch runs a method.
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		version := ProtocolVersion
+		for _, v := range supportedVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities": map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{},
+			},
+			"serverInfo":   map[string]string{"name": s.name, "version": s.version},
+			"instructions": s.Instructions,
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		tools := s.tools
+		if tools == nil {
+			tools = []Tool{}
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		for _, t := range s.tools {
+			if t.Name == p.Name {
+				return callTool(ctx, t, p.Arguments), nil
+			}
+		}
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+
+	case "resources/list":
+		return map[string]interface{}{"resources": []ResourceContents{}}, nil
+
+	case "resources/templates/list":
+		templates := s.templates
+		if templates == nil {
+			templates = []ResourceTemplate{}
+		}
+		return map[string]interface{}{"resourceTemplates": templates}, nil
+
+	case "resources/read":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		for i := range s.templates {
+			t := &s.templates[i]
+			vars, ok := t.match(p.URI)
+			if !ok {
+				continue
+			}
+			contents, err := t.Read(ctx, p.URI, vars)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"contents": []*ResourceContents{contents}}, nil
+		}
+		return nil, &Error{Code: CodeResourceNotFound, Message: fmt.Sprintf("resource not found: %s", p.URI)}
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+// callTool runs t, turning an error into an error result and a nil result
+// into an empty one.
+func callTool(ctx context.Context, t Tool, args json.RawMessage) (res *ToolResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = TextResult(fmt.Sprintf("internal error: %v", r))
+			res.IsError = true
+		}
+	}()
+	res, err := t.Handler(ctx, args)
+	switch {
+	case err != nil:
+		res = TextResult(err.Error())
+		res.IsError = true
+	case res == nil:
+		res = TextResult("")
+	}
+	return res
+}
+
+// DecodeArgs decodes the arguments of a tool call into v, if there are
+// any.
+func DecodeArgs(args json.RawMessage, v interface{}) error {
+	if len(args) == 0 || string(args) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// decodeParams decodes the params of a request into v, if there are any.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testServer returns a server with an echo tool, a failing tool, a
+// panicking tool, a tool without a result and a resource template.
+func testServer() *Server {
+	s := NewServer("test", "1.0")
+	s.Instructions = "test instructions"
+	s.AddTool(Tool{
+		Name:        "echo",
+		Description: "echo the text argument",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (*ToolResult, error) {
+			var args struct {
+				Text string `json:"text"`
+			}
+			if err := DecodeArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			return JSONResult(map[string]string{"text": args.Text})
+		},
+	})
+	s.AddTool(Tool{
+		Name:        "fail",
+		InputSchema: json.RawMessage(`{"type":"object"}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (*ToolResult, error) {
+			return nil, errors.New("tool failed")
+		},
+	})
+	s.AddTool(Tool{
+		Name:        "panic",
+		InputSchema: json.RawMessage(`{"type":"object"}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (*ToolResult, error) {
+			panic("boom")
+		},
+	})
+	s.AddTool(Tool{
+		Name:        "nothing",
+		InputSchema: json.RawMessage(`{"type":"object"}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (*ToolResult, error) {
+			return nil, nil
+		},
+	})
+	s.AddResourceTemplate(ResourceTemplate{
+		URITemplate: "test://items/{id}/parts/{part}",
+		Name:        "part",
+		MIMEType:    "text/plain",
+		Read: func(ctx context.Context, uri string, vars map[string]string) (*ResourceContents, error) {
+			if vars["id"] == "missing" {
+				return nil, &Error{Code: CodeResourceNotFound, Message: "no such item"}
+			}
+			if vars["id"] == "broken" {
+				return nil, errors.New("read failed")
+			}
+			return &ResourceContents{URI: uri, MIMEType: "text/plain", Text: vars["id"] + "|" + vars["part"]}, nil
+		},
+	})
+	return s
+}
+
+// testResponse is a decoded response.
+type testResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// serve runs s on the given input lines and returns the responses.
+func serve(t *testing.T, s *Server, lines ...string) []testResponse {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var resps []testResponse
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r testResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		if r.JSONRPC != "2.0" {
+			t.Errorf("response jsonrpc = %q", r.JSONRPC)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+// call sends one request to s and returns its response.
+func call(t *testing.T, s *Server, method, params string) testResponse {
+	t.Helper()
+	line := `{"jsonrpc":"2.0","id":1,"method":"` + method + `"`
+	if params != "" {
+		line += `,"params":` + params
+	}
+	resps := serve(t, s, line+"}")
+	if len(resps) != 1 {
+		t.Fatalf("got %d responses, want 1", len(resps))
+	}
+	return resps[0]
+}
+
+func TestInitialize(t *testing.T) {
+	tests := []struct {
+		requested, want string
+	}{
+		{ProtocolVersion, ProtocolVersion},
+		{"2025-03-26", "2025-03-26"},
+		{"2024-11-05", "2024-11-05"},
+		{"1999-01-01", ProtocolVersion},
+		{"", ProtocolVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requested, func(t *testing.T) {
+			resp := call(t, testServer(), "initialize", `{"protocolVersion":"`+tt.requested+`","capabilities":{},"clientInfo":{"name":"c","version":"1"}}`)
+			if resp.Error != nil {
+				t.Fatalf("error: %v", resp.Error)
+			}
+			var result struct {
+				ProtocolVersion string                     `json:"protocolVersion"`
+				Capabilities    map[string]json.RawMessage `json:"capabilities"`
+				ServerInfo      struct{ Name, Version string }
+				Instructions    string `json:"instructions"`
+			}
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.ProtocolVersion != tt.want {
+				t.Errorf("protocolVersion = %q, want %q", result.ProtocolVersion, tt.want)
+			}
+			if result.ServerInfo.Name != "test" || result.ServerInfo.Version != "1.0" {
+				t.Errorf("serverInfo = %+v", result.ServerInfo)
+			}
+			if _, ok := result.Capabilities["tools"]; !ok {
+				t.Error("tools capability missing")
+			}
+			if _, ok := result.Capabilities["resources"]; !ok {
+				t.Error("resources capability missing")
+			}
+			if result.Instructions != "test instructions" {
+				t.Errorf("instructions = %q", result.Instructions)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantID   string
+		wantCode int // 0 for a result
+	}{
+		{"ping", `{"jsonrpc":"2.0","id":7,"method":"ping"}`, "7", 0},
+		{"string id", `{"jsonrpc":"2.0","id":"abc","method":"ping"}`, `"abc"`, 0},
+		{"unknown method", `{"jsonrpc":"2.0","id":2,"method":"prompts/list"}`, "2", CodeMethodNotFound},
+		{"batch", `[{"jsonrpc":"2.0","id":3,"method":"ping"}]`, "null", CodeInvalidRequest},
+		{"parse error", `{"jsonrpc":`, "null", CodeParseError},
+		{"wrong version", `{"jsonrpc":"1.0","id":4,"method":"ping"}`, "4", CodeInvalidRequest},
+		{"no method", `{"jsonrpc":"2.0","id":5}`, "5", CodeInvalidRequest},
+		{"invalid params", `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":[1]}`, "6", CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resps := serve(t, testServer(), tt.line)
+			if len(resps) != 1 {
+				t.Fatalf("got %d responses, want 1", len(resps))
+			}
+			resp := resps[0]
+			if string(resp.ID) != tt.wantID {
+				t.Errorf("id = %s, want %s", resp.ID, tt.wantID)
+			}
+			switch {
+			case tt.wantCode == 0 && resp.Error != nil:
+				t.Errorf("error %d %s, want a result", resp.Error.Code, resp.Error.Message)
+			case tt.wantCode != 0 && resp.Error == nil:
+				t.Errorf("result %s, want error %d", resp.Result, tt.wantCode)
+			case tt.wantCode != 0 && resp.Error.Code != tt.wantCode:
+				t.Errorf("error code %d, want %d", resp.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	resps := serve(t, testServer(),
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`,
+		`{"jsonrpc":"2.0","method":"no/such/method"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+	)
+	if len(resps) != 1 || string(resps[0].ID) != "1" {
+		t.Errorf("got %+v, want only the ping response", resps)
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	resp := call(t, testServer(), "tools/list", "")
+	var result struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			InputSchema json.RawMessage `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		if len(tool.InputSchema) == 0 {
+			t.Errorf("tool %s has no inputSchema", tool.Name)
+		}
+	}
+	if got := strings.Join(names, ","); got != "echo,fail,panic,nothing" {
+		t.Errorf("tools = %s", got)
+	}
+
+	resp = call(t, NewServer("empty", "1"), "tools/list", "")
+	if string(resp.Result) != `{"tools":[]}` {
+		t.Errorf("tools/list of an empty server = %s", resp.Result)
+	}
+}
+
+func TestToolsCall(t *testing.T) {
+	tests := []struct {
+		name, params string
+		wantCode     int // JSON-RPC error code, or 0
+		wantError    bool
+		wantText     string
+	}{
+		{"result", `{"name":"echo","arguments":{"text":"hi"}}`, 0, false, `"text": "hi"`},
+		{"no arguments", `{"name":"echo"}`, 0, false, `"text": ""`},
+		{"bad arguments", `{"name":"echo","arguments":{"text":1}}`, 0, true, "invalid arguments"},
+		{"handler error", `{"name":"fail","arguments":{}}`, 0, true, "tool failed"},
+		{"handler panic", `{"name":"panic","arguments":{}}`, 0, true, "internal error: boom"},
+		{"unknown tool", `{"name":"nope","arguments":{}}`, CodeInvalidParams, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := call(t, testServer(), "tools/call", tt.params)
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("got %s, %+v; want error %d", resp.Result, resp.Error, tt.wantCode)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("error %d %s", resp.Error.Code, resp.Error.Message)
+			}
+			var result ToolResult
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.wantError {
+				t.Errorf("isError = %v, want %v", result.IsError, tt.wantError)
+			}
+			if len(result.Content) != 1 || result.Content[0].Type != "text" || !strings.Contains(result.Content[0].Text, tt.wantText) {
+				t.Errorf("content = %+v, want text containing %q", result.Content, tt.wantText)
+			}
+			if !tt.wantError && result.StructuredContent == nil {
+				t.Error("structuredContent missing")
+			}
+		})
+	}
+}
+
+func TestToolsCallNilResult(t *testing.T) {
+	resp := call(t, testServer(), "tools/call", `{"name":"nothing"}`)
+	if resp.Error != nil {
+		t.Fatalf("error %d %s", resp.Error.Code, resp.Error.Message)
+	}
+	var result ToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "" {
+		t.Errorf("result = %s, want an empty text result", resp.Result)
+	}
+}
+
+func TestResources(t *testing.T) {
+	s := testServer()
+
+	resp := call(t, s, "resources/list", "")
+	if string(resp.Result) != `{"resources":[]}` {
+		t.Errorf("resources/list = %s", resp.Result)
+	}
+
+	resp = call(t, s, "resources/templates/list", "")
+	var templates struct {
+		ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	}
+	if err := json.Unmarshal(resp.Result, &templates); err != nil {
+		t.Fatal(err)
+	}
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != "test://items/{id}/parts/{part}" {
+		t.Errorf("resources/templates/list = %s", resp.Result)
+	}
+
+	tests := []struct {
+		name, uri string
+		wantCode  int // JSON-RPC error code, or 0
+		wantText  string
+	}{
+		{"match", "test://items/a1/parts/p2", 0, "a1|p2"},
+		{"unescaped", "test://items/a%2Fb%20c/parts/%E2%9C%93", 0, "a/b c|✓"},
+		{"prefix mismatch", "test://itemsX/a1/parts/p2", CodeResourceNotFound, ""},
+		{"empty variable", "test://items//parts/p2", CodeResourceNotFound, ""},
+		{"extra segment", "test://items/a1/b/parts/p2", CodeResourceNotFound, ""},
+		{"suffix", "test://items/a1/parts/p2/more", CodeResourceNotFound, ""},
+		{"other scheme", "other://items/a1/parts/p2", CodeResourceNotFound, ""},
+		{"bad escape", "test://items/%zz/parts/p2", CodeResourceNotFound, ""},
+		{"reader not found", "test://items/missing/parts/p2", CodeResourceNotFound, ""},
+		{"reader error", "test://items/broken/parts/p2", CodeInternalError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := call(t, s, "resources/read", `{"uri":"`+tt.uri+`"}`)
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("got %s, %+v; want error %d", resp.Result, resp.Error, tt.wantCode)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("error %d %s", resp.Error.Code, resp.Error.Message)
+			}
+			var result struct {
+				Contents []ResourceContents `json:"contents"`
+			}
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Contents) != 1 || result.Contents[0].Text != tt.wantText || result.Contents[0].URI != tt.uri {
+				t.Errorf("contents = %+v, want %q", result.Contents, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestOversizedMessage(t *testing.T) {
+	huge := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"pad":"` + strings.Repeat("x", maxMessage) + `"}}`
+	resps := serve(t, testServer(), huge, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if len(resps) != 2 {
+		t.Fatalf("got %d responses, want 2", len(resps))
+	}
+	if resps[0].Error == nil || resps[0].Error.Code != CodeParseError || string(resps[0].ID) != "null" {
+		t.Errorf("oversized message: got %+v, want a parse error", resps[0])
+	}
+	if resps[1].Error != nil || string(resps[1].ID) != "2" {
+		t.Errorf("next message: got %+v, want the ping response", resps[1])
+	}
+}
+
+func TestServeStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// A reader that never ends: Serve must return once ctx is done.
+	r, w := io.Pipe()
+	defer w.Close()
+	if err := testServer().Serve(ctx, r, &bytes.Buffer{}); err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
@@ -0,0 +1,430 @@
+// Package mirror maintains a local copy of the Manuals catalog.
+//
+// A mirror directory has the following layout:
+//
+//	manifest.json            sync state (see Manifest)
+//	index.json               full-text search index over devices
+//	devices/<id>.json        devices, including their content
+//	documents/<id>.json      document metadata
+//	files/<device-id>/<name> document files, if synced with Files set
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/output"
+	"github.com/rmrfslashbin/manuals-cli/internal/safename"
+)
+
+// ManifestVersion is the current manifest format version.
+const ManifestVersion = 1
+
+// Manifest records the state of a mirror after a sync.
+type Manifest struct {
+	Version   int                      `json:"version"`
+	APIURL    string                   `json:"api_url,omitempty"`
+	LastSync  time.Time                `json:"last_sync"`
+	Devices   map[string]DeviceState   `json:"devices"`
+	Documents map[string]DocumentState `json:"documents"`
+}
+
+// DeviceState is the mirrored version of a device.
+type DeviceState struct {
+	IndexedAt string `json:"indexed_at"`
+}
+
+// DocumentState is the mirrored version of a document.
+type DocumentState struct {
+	IndexedAt string `json:"indexed_at"`
+	Checksum  string `json:"checksum,omitempty"`
+
+	// File is the path of the downloaded file relative to the mirror
+	// directory, if it was downloaded.
+	File string `json:"file,omitempty"`
+}
+
+// Mirror is a local catalog mirror rooted at a directory.
+type Mirror struct {
+	dir string
+}
+
+// Open returns the mirror in dir. The directory need not exist yet.
+func Open(dir string) *Mirror {
+	return &Mirror{dir: dir}
+}
+
+// Dir returns the mirror directory.
+func (m *Mirror) Dir() string {
+	return m.dir
+}
+
+// Manifest loads the mirror manifest. A missing manifest yields an empty
+// one.
+func (m *Mirror) Manifest() (*Manifest, error) {
+	man := &Manifest{
+		Version:   ManifestVersion,
+		Devices:   map[string]DeviceState{},
+		Documents: map[string]DocumentState{},
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.dir, "manifest.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return man, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, man); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if man.Version > ManifestVersion {
+		return nil, fmt.Errorf("manifest version %d is newer than supported version %d", man.Version, ManifestVersion)
+	}
+	if man.Devices == nil {
+		man.Devices = map[string]DeviceState{}
+	}
+	if man.Documents == nil {
+		man.Documents = map[string]DocumentState{}
+	}
+	return man, nil
+}
+
+// SaveManifest writes the mirror manifest.
+func (m *Mirror) SaveManifest(man *Manifest) error {
+	man.Version = ManifestVersion
+	return m.writeJSON("manifest.json", man)
+}
+
+// Device loads a mirrored device.
+func (m *Mirror) Device(id string) (*client.Device, error) {
+	if err := safename.Check(id); err != nil {
+		return nil, err
+	}
+	var d client.Device
+	if err := m.readJSON(filepath.Join("devices", id+".json"), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// WalkDevices calls fn for every mirrored device, stopping at the first
+// error.
+func (m *Mirror) WalkDevices(fn func(*client.Device) error) error {
+	entries, err := os.ReadDir(filepath.Join(m.dir, "devices"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		var d client.Device
+		if err := m.readJSON(filepath.Join("devices", e.Name()), &d); err != nil {
+			return err
+		}
+		if err := fn(&d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Document loads mirrored document metadata.
+func (m *Mirror) Document(id string) (*client.Document, error) {
+	if err := safename.Check(id); err != nil {
+		return nil, err
+	}
+	var d client.Document
+	if err := m.readJSON(filepath.Join("documents", id+".json"), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Options controls Sync.
+type Options struct {
+	// Files also downloads document files.
+	Files bool
+
+	// Progress, if set, is called with a short description of each change.
+	Progress func(msg string)
+}
+
+// Result summarizes a sync.
+type Result struct {
+	DevicesAdded     int `json:"devices_added"`
+	DevicesUpdated   int `json:"devices_updated"`
+	DevicesRemoved   int `json:"devices_removed"`
+	DevicesUnchanged int `json:"devices_unchanged"`
+
+	DocumentsAdded     int `json:"documents_added"`
+	DocumentsUpdated   int `json:"documents_updated"`
+	DocumentsRemoved   int `json:"documents_removed"`
+	DocumentsUnchanged int `json:"documents_unchanged"`
+
+	FilesDownloaded int   `json:"files_downloaded"`
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+
+	// Failures are the document files that could not be downloaded.
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Failure is a document file that failed to download.
+type Failure struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Error    string `json:"error"`
+
+	// Err is the download error.
+	Err error `json:"-"`
+}
+
+// Sync brings the mirror up to date with the server. The catalog is listed
+// in full, but device details and document files are only fetched for
+// entries whose IndexedAt differs from the manifest (or whose local copy is
+// missing). Entries no longer on the server are removed. The manifest is
+// saved even if the sync fails part way, so the next run picks up where
+// this one stopped.
+//
+// Cached responses of c are revalidated, so the mirror is never older than
+// the server. Entries whose IDs or filenames cannot be used as file names
+// are skipped. A document file that fails to download, as when it does
+// not match its checksum, is recorded in the result's Failures and retried
+// by the next sync; the other files are still synced.
+func (m *Mirror) Sync(ctx context.Context, c *client.Client, apiURL string, opts Options) (res *Result, err error) {
+	man, err := m.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	man.APIURL = apiURL
+	c = c.With(client.WithRevalidate(true))
+
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	res = &Result{}
+	defer func() {
+		if serr := m.SaveManifest(man); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	// Devices
+	seen := map[string]bool{}
+	for d, err := range c.AllDevices(ctx, 0, "", "") {
+		if err != nil {
+			return res, fmt.Errorf("failed to list devices: %w", err)
+		}
+		if err := safename.Check(d.ID); err != nil {
+			progress("skipped device: " + err.Error())
+			continue
+		}
+		seen[d.ID] = true
+
+		state, known := man.Devices[d.ID]
+		if known && state.IndexedAt == d.IndexedAt && m.exists(filepath.Join("devices", d.ID+".json")) {
+			res.DevicesUnchanged++
+			continue
+		}
+
+		full, err := c.GetDeviceContext(ctx, d.ID)
+		if err != nil {
+			return res, fmt.Errorf("failed to get device %s: %w", d.ID, err)
+		}
+		m.invalidateIndex()
+		if err := m.writeJSON(filepath.Join("devices", d.ID+".json"), full); err != nil {
+			return res, err
+		}
+		man.Devices[d.ID] = DeviceState{IndexedAt: d.IndexedAt}
+
+		if known {
+			res.DevicesUpdated++
+			progress("updated device " + d.Name)
+		} else {
+			res.DevicesAdded++
+			progress("added device " + d.Name)
+		}
+	}
+	for id := range man.Devices {
+		if !seen[id] {
+			m.invalidateIndex()
+			_ = os.Remove(filepath.Join(m.dir, "devices", id+".json"))
+			delete(man.Devices, id)
+			res.DevicesRemoved++
+			progress("removed device " + id)
+		}
+	}
+
+	// Documents. files maps the downloaded files to their documents, so
+	// documents of a device sharing a filename get distinct files.
+	files := map[string]string{}
+	for id, state := range man.Documents {
+		if state.File != "" {
+			files[state.File] = id
+		}
+	}
+	seen = map[string]bool{}
+	for d, err := range c.AllDocuments(ctx, 0, "") {
+		if err != nil {
+			return res, fmt.Errorf("failed to list documents: %w", err)
+		}
+		if err := safename.Check(d.ID); err != nil {
+			progress("skipped document: " + err.Error())
+			continue
+		}
+		seen[d.ID] = true
+
+		state, known := man.Documents[d.ID]
+		changed := !known || state.IndexedAt != d.IndexedAt || state.Checksum != d.Checksum ||
+			!m.exists(filepath.Join("documents", d.ID+".json"))
+
+		if changed {
+			if err := m.writeJSON(filepath.Join("documents", d.ID+".json"), d); err != nil {
+				return res, err
+			}
+			if known {
+				res.DocumentsUpdated++
+				progress("updated document " + d.Filename)
+			} else {
+				res.DocumentsAdded++
+				progress("added document " + d.Filename)
+			}
+		} else {
+			res.DocumentsUnchanged++
+		}
+
+		newState := DocumentState{IndexedAt: d.IndexedAt, Checksum: d.Checksum, File: state.File}
+		if opts.Files && (changed || state.File == "" || !m.exists(state.File)) {
+			rel, err := fileName(d, files)
+			if err != nil {
+				progress(fmt.Sprintf("skipped file of document %s: %v", d.ID, err))
+				man.Documents[d.ID] = newState
+				continue
+			}
+			if err := os.MkdirAll(filepath.Join(m.dir, filepath.Dir(rel)), 0o755); err != nil {
+				return res, err
+			}
+			dl := client.DownloadOptions{Resume: true, Overwrite: true}
+			if d.Checksum != "" {
+				if _, err := checksum.New(d.Checksum); err != nil {
+					progress(fmt.Sprintf("not verifying %s: %v", d.Filename, err))
+				} else {
+					dl.Checksum = d.Checksum
+				}
+			}
+			written, err := c.DownloadFile(ctx, d.ID, filepath.Join(m.dir, rel), dl)
+			if err != nil {
+				// An empty IndexedAt makes the next sync retry the file.
+				man.Documents[d.ID] = DocumentState{File: state.File}
+				if ctx.Err() != nil {
+					return res, fmt.Errorf("failed to download %s: %w", d.Filename, err)
+				}
+				res.Failures = append(res.Failures, Failure{ID: d.ID, Filename: d.Filename, Error: err.Error(), Err: err})
+				progress(fmt.Sprintf("failed to download %s: %v", d.Filename, err))
+				continue
+			}
+			// The file moves when the document's device or filename
+			// changes; remove the old one.
+			if state.File != "" && state.File != rel {
+				_ = os.Remove(filepath.Join(m.dir, state.File))
+				delete(files, state.File)
+			}
+			files[rel] = d.ID
+			newState.File = rel
+			res.FilesDownloaded++
+			res.BytesDownloaded += written
+			progress("downloaded " + rel)
+		}
+		man.Documents[d.ID] = newState
+	}
+	for id, state := range man.Documents {
+		if !seen[id] {
+			_ = os.Remove(filepath.Join(m.dir, "documents", id+".json"))
+			if state.File != "" {
+				_ = os.Remove(filepath.Join(m.dir, state.File))
+			}
+			delete(man.Documents, id)
+			res.DocumentsRemoved++
+			progress("removed document " + id)
+		}
+	}
+
+	if !m.exists("index.json") {
+		if _, err := m.BuildIndex(); err != nil {
+			return res, fmt.Errorf("failed to build search index: %w", err)
+		}
+	}
+
+	man.LastSync = time.Now().UTC()
+	return res, nil
+}
+
+// fileName returns the path, relative to the mirror, of the file of d:
+// files/<device-id>/<filename>, with d's short ID added before the
+// extension if another document in files already has that path.
+func fileName(d client.Document, files map[string]string) (string, error) {
+	if err := safename.Check(d.DeviceID); err != nil {
+		return "", err
+	}
+	if err := safename.Check(d.Filename); err != nil {
+		return "", err
+	}
+	rel := filepath.Join("files", d.DeviceID, d.Filename)
+	if id, ok := files[rel]; ok && id != d.ID {
+		ext := filepath.Ext(d.Filename)
+		rel = filepath.Join("files", d.DeviceID, strings.TrimSuffix(d.Filename, ext)+"-"+output.ShortIDCell(d.ID)+ext)
+	}
+	return rel, nil
+}
+
+// exists reports whether a path relative to the mirror exists.
+func (m *Mirror) exists(rel string) bool {
+	_, err := os.Stat(filepath.Join(m.dir, rel))
+	return err == nil
+}
+
+// readJSON decodes a file relative to the mirror directory.
+func (m *Mirror) readJSON(rel string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(m.dir, rel))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", rel, err)
+	}
+	return nil
+}
+
+// writeJSON atomically writes v as JSON to a path relative to the mirror.
+func (m *Mirror) writeJSON(rel string, v interface{}) error {
+	path := filepath.Join(m.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,113 @@
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/checksum"
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+)
+
+// newTestServer serves a catalog of one device with the given documents,
+// each of whose files holds its filename.
+func newTestServer(t *testing.T, docs []client.Document) *client.Client {
+	t.Helper()
+	device := client.Device{ID: "dev1", Name: "Board", IndexedAt: "2025-01-01"}
+	prefix := "/api/" + client.APIVersion
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		offset := r.URL.Query().Get("offset")
+		switch {
+		case path == "/devices":
+			resp := client.DevicesResponse{Data: []client.Device{device}, Total: 1}
+			if offset != "" && offset != "0" {
+				resp.Data = nil
+			}
+			json.NewEncoder(w).Encode(resp)
+		case path == "/devices/dev1":
+			json.NewEncoder(w).Encode(device)
+		case path == "/documents":
+			resp := client.DocumentsResponse{Data: docs, Total: len(docs)}
+			if offset != "" && offset != "0" {
+				resp.Data = nil
+			}
+			json.NewEncoder(w).Encode(resp)
+		case strings.HasPrefix(path, "/documents/") && strings.HasSuffix(path, "/download"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "/documents/"), "/download")
+			for _, d := range docs {
+				if d.ID == id {
+					w.Write([]byte(d.Filename))
+					return
+				}
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return client.New(srv.URL, "test-key")
+}
+
+// sha256Of returns the hex SHA-256 digest of s.
+func sha256Of(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSyncFileFailures(t *testing.T) {
+	docs := []client.Document{
+		{ID: "doc1", DeviceID: "dev1", Filename: "good.pdf", Checksum: sha256Of("good.pdf"), IndexedAt: "1"},
+		{ID: "doc2", DeviceID: "dev1", Filename: "bad.pdf", Checksum: sha256Of("something else"), IndexedAt: "1"},
+		{ID: "doc3", DeviceID: "dev1", Filename: "odd.pdf", Checksum: "crc32:1234abcd", IndexedAt: "1"},
+		{ID: "doc4", DeviceID: "dev1", Filename: "../escape.pdf", IndexedAt: "1"},
+	}
+	c := newTestServer(t, docs)
+	m := Open(t.TempDir())
+
+	var messages []string
+	res, err := m.Sync(context.Background(), c, "", Options{
+		Files:    true,
+		Progress: func(msg string) { messages = append(messages, msg) },
+	})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if res.FilesDownloaded != 2 {
+		t.Errorf("downloaded %d files, want 2", res.FilesDownloaded)
+	}
+	if len(res.Failures) != 1 || res.Failures[0].ID != "doc2" {
+		t.Fatalf("failures = %+v, want doc2", res.Failures)
+	}
+	var mismatch *checksum.MismatchError
+	if !errors.As(res.Failures[0].Err, &mismatch) {
+		t.Errorf("failure error = %v, want *checksum.MismatchError", res.Failures[0].Err)
+	}
+	if !strings.Contains(strings.Join(messages, "\n"), "not verifying odd.pdf") {
+		t.Errorf("no warning for the unsupported checksum in %q", messages)
+	}
+
+	for _, name := range []string{"good.pdf", "odd.pdf"} {
+		if _, err := os.Stat(filepath.Join(m.Dir(), "files", "dev1", name)); err != nil {
+			t.Errorf("file %s: %v", name, err)
+		}
+	}
+
+	// The failed file is retried by the next sync, and only it.
+	res, err = m.Sync(context.Background(), c, "", Options{Files: true})
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if res.FilesDownloaded != 0 || len(res.Failures) != 1 {
+		t.Errorf("second sync downloaded %d and failed %d files, want 0 and 1", res.FilesDownloaded, len(res.Failures))
+	}
+}
@@ -0,0 +1,111 @@
+package mirror
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rmrfslashbin/manuals-cli/internal/client"
+	"github.com/rmrfslashbin/manuals-cli/internal/index"
+	"github.com/rmrfslashbin/manuals-cli/internal/output"
+)
+
+// snippetLen is the length, in terminal columns, of snippets built from
+// local content.
+const snippetLen = 240
+
+// ErrNotSynced is returned when searching a mirror that has no devices.
+var ErrNotSynced = errors.New("no local catalog: run 'manuals sync' first")
+
+// indexPath returns the path of the search index.
+func (m *Mirror) indexPath() string {
+	return filepath.Join(m.dir, "index.json")
+}
+
+// invalidateIndex removes the search index so it is rebuilt after the
+// devices change, even if the sync that changed them is interrupted.
+func (m *Mirror) invalidateIndex() {
+	_ = os.Remove(m.indexPath())
+}
+
+// BuildIndex indexes every mirrored device and saves the index.
+func (m *Mirror) BuildIndex() (*index.Index, error) {
+	ix := index.New()
+	err := m.WalkDevices(func(d *client.Device) error {
+		ix.Add(d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.Save(m.indexPath()); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Index loads the search index, building it if it does not exist.
+func (m *Mirror) Index() (*index.Index, error) {
+	ix, err := index.Load(m.indexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		if !m.exists("devices") {
+			return nil, ErrNotSynced
+		}
+		return m.BuildIndex()
+	}
+	return ix, err
+}
+
+// Search searches the mirrored devices matching filter, returning results
+// in the same shape as the server's search endpoint.
+func (m *Mirror) Search(query string, limit int, filter client.SearchFilter) (*client.SearchResponse, error) {
+	ix, err := m.Index()
+	if err != nil {
+		return nil, err
+	}
+	if len(ix.Docs) == 0 {
+		return nil, ErrNotSynced
+	}
+
+	terms := index.Tokenize(query)
+	resp := &client.SearchResponse{
+		Results: []client.SearchResult{},
+		Query:   query,
+	}
+	n := limit
+	if !filter.IsZero() {
+		n = 0
+	}
+	var hits []client.SearchResult
+	for _, hit := range ix.Search(query, n) {
+		hits = append(hits, client.SearchResult{
+			DeviceID: hit.Doc.ID,
+			Name:     hit.Doc.Name,
+			Domain:   hit.Doc.Domain,
+			Type:     hit.Doc.Type,
+			Path:     hit.Doc.Path,
+			Score:    hit.Score,
+		})
+	}
+	hits, err = filter.Apply(hits, limit, func(r client.SearchResult) (map[string]interface{}, error) {
+		d, err := m.Device(r.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		return d.Metadata, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range hits {
+		if d, err := m.Device(r.DeviceID); err == nil {
+			r.Snippet = output.Excerpt(d.Content, terms, snippetLen)
+		}
+		resp.Results = append(resp.Results, r)
+	}
+	resp.Total = len(resp.Results)
+
+	return resp, nil
+}
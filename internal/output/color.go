@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI styles used for colored output.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiGreen     = "\x1b[32m"
+	ansiYellow    = "\x1b[33m"
+	ansiMagenta   = "\x1b[35m"
+	ansiCyan      = "\x1b[36m"
+)
+
+// Color modes accepted by SetColor.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// SetColor sets when output is colored: always, never, or auto (or "") to
+// color only when stdout is a terminal, NO_COLOR is not set and TERM is
+// not "dumb".
+func (w *Writer) SetColor(mode string) error {
+	switch mode {
+	case "", ColorAuto:
+		w.color = w.tty && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	case ColorAlways:
+		w.color = true
+	case ColorNever:
+		w.color = false
+	default:
+		return fmt.Errorf("invalid color mode %q (use auto, always or never)", mode)
+	}
+	return nil
+}
+
+// Score formats a relevance score with two decimals, colored by strength
+// when color is enabled.
+func (w *Writer) Score(score float64) string {
+	s := fmt.Sprintf("%.2f", score)
+	switch {
+	case score >= 0.75:
+		return w.style(s, ansiGreen)
+	case score >= 0.5:
+		return w.style(s, ansiYellow)
+	}
+	return s
+}
+
+// style wraps s in an ANSI style if color is enabled.
+func (w *Writer) style(s, code string) string {
+	if !w.color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
@@ -0,0 +1,626 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled JSONPath template in the style of kubectl:
+// literal text with expressions in braces, such as
+//
+//	{.data[*].id}
+//	{range .results[*]}{.device_id}{"\t"}{.name}{"\n"}{end}
+//
+// Paths select from the JSON encoding of a value and support .field,
+// ['field'], [n], [start:end], [*], .*, ..field (recursive descent) and
+// filters like [?(@.type=="sensors")]. Several results of one expression
+// are separated by spaces. Within range, paths are relative to the
+// current item; $ always refers to the root.
+type JSONPath struct {
+	nodes []jpNode
+}
+
+// jpNode is a piece of a JSONPath template.
+type jpNode struct {
+	text  string   // literal text, if path is nil and body is nil
+	path  []jpStep // expression to print, or to range over if body is set
+	body  []jpNode // range body
+	root  bool     // path starts at the root ($)
+	isVal bool     // node prints path (as opposed to literal text)
+}
+
+// jpStep is one selector of a path.
+type jpStep struct {
+	kind       jpKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jpFilter
+}
+
+type jpKind int
+
+const (
+	jpField jpKind = iota
+	jpWildcard
+	jpRecursive // name is the field, or "*" for every descendant
+	jpIndex
+	jpSlice
+	jpSelect
+)
+
+// jpFilter is a [?(@.path op literal)] filter. An empty op tests that
+// path exists and is not false or null.
+type jpFilter struct {
+	path  []jpStep
+	op    string
+	value interface{}
+}
+
+// ParseJSONPath compiles a JSONPath template.
+func ParseJSONPath(s string) (*JSONPath, error) {
+	nodes, rest, err := parseJPNodes(s, false)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("jsonpath: {end} without {range}")
+	}
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// parseJPNodes parses template nodes until the end of s or, in a range
+// body, until {end}. It returns the unparsed remainder after {end}.
+func parseJPNodes(s string, inRange bool) ([]jpNode, string, error) {
+	var nodes []jpNode
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			nodes = append(nodes, jpNode{text: s})
+			s = ""
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jpNode{text: s[:open]})
+		}
+		end, err := closingBrace(s, open)
+		if err != nil {
+			return nil, "", err
+		}
+		expr := strings.TrimSpace(s[open+1 : end])
+		s = s[end+1:]
+
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("jsonpath: {end} without {range}")
+			}
+			return nodes, s, nil
+		case strings.HasPrefix(expr, "range "):
+			node, err := parseJPPath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			body, rest, err := parseJPNodes(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			if body == nil {
+				body = []jpNode{}
+			}
+			node.body = body
+			nodes = append(nodes, node)
+			s = rest
+		case strings.HasPrefix(expr, `"`):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, "", fmt.Errorf("jsonpath: invalid string %s", expr)
+			}
+			nodes = append(nodes, jpNode{text: text})
+		default:
+			node, err := parseJPPath(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("jsonpath: {range} without {end}")
+	}
+	return nodes, "", nil
+}
+
+// closingBrace returns the index of the brace closing the one at open,
+// skipping quoted strings.
+func closingBrace(s string, open int) (int, error) {
+	var quote byte
+	for i := open + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("jsonpath: unclosed { in %q", s[open:])
+}
+
+// parseJPPath parses a path expression into a printing node.
+func parseJPPath(expr string) (jpNode, error) {
+	node := jpNode{isVal: true}
+	p := expr
+	if strings.HasPrefix(p, "$") {
+		node.root = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, "@") {
+		p = p[1:]
+	}
+	steps, err := parseJPSteps(p)
+	if err != nil {
+		return jpNode{}, fmt.Errorf("jsonpath: %q: %w", expr, err)
+	}
+	node.path = steps
+	return node, nil
+}
+
+// parseJPSteps parses the selectors of a path.
+func parseJPSteps(p string) ([]jpStep, error) {
+	steps := []jpStep{}
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			p = p[2:]
+			name := identPrefix(p)
+			if name == "" {
+				return nil, fmt.Errorf("field name expected after ..")
+			}
+			steps = append(steps, jpStep{kind: jpRecursive, name: name})
+			p = p[len(name):]
+		case p[0] == '.':
+			p = p[1:]
+			name := identPrefix(p)
+			switch name {
+			case "":
+				if p != "" && p[0] != '[' {
+					return nil, fmt.Errorf("field name expected after .")
+				}
+			case "*":
+				steps = append(steps, jpStep{kind: jpWildcard})
+			default:
+				steps = append(steps, jpStep{kind: jpField, name: name})
+			}
+			p = p[len(name):]
+		case p[0] == '[':
+			end, err := closingBracket(p)
+			if err != nil {
+				return nil, err
+			}
+			step, err := parseJPBracket(strings.TrimSpace(p[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", p)
+		}
+	}
+	return steps, nil
+}
+
+// identPrefix returns the field name or * at the start of p.
+func identPrefix(p string) string {
+	if strings.HasPrefix(p, "*") {
+		return "*"
+	}
+	i := strings.IndexAny(p, ".[")
+	if i < 0 {
+		return p
+	}
+	return p[:i]
+}
+
+// closingBracket returns the index of the ] closing the [ at p[0],
+// skipping quoted strings and nested brackets.
+func closingBracket(p string) (int, error) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed [")
+}
+
+// parseJPBracket parses the contents of a [...] selector.
+func parseJPBracket(s string) (jpStep, error) {
+	switch {
+	case s == "*":
+		return jpStep{kind: jpWildcard}, nil
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		f, err := parseJPFilter(strings.TrimSpace(s[2 : len(s)-1]))
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpSelect, filter: f}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := unquoteJP(s)
+		if err != nil {
+			return jpStep{}, err
+		}
+		return jpStep{kind: jpField, name: name}, nil
+	case strings.Contains(s, ":"):
+		lo, hi, _ := strings.Cut(s, ":")
+		step := jpStep{kind: jpSlice}
+		for _, b := range []struct {
+			s   string
+			dst **int
+		}{{lo, &step.start}, {hi, &step.end}} {
+			if b.s = strings.TrimSpace(b.s); b.s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(b.s)
+			if err != nil {
+				return jpStep{}, fmt.Errorf("invalid slice [%s]", s)
+			}
+			*b.dst = &n
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return jpStep{}, fmt.Errorf("invalid selector [%s]", s)
+		}
+		return jpStep{kind: jpIndex, index: n}, nil
+	}
+}
+
+// jpOps are the filter comparison operators, longest first.
+var jpOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseJPFilter parses a filter expression such as @.type=="sensors".
+func parseJPFilter(s string) (*jpFilter, error) {
+	var left, op, right string
+	left = s
+	for _, candidate := range jpOps {
+		if i := indexUnquoted(s, candidate); i >= 0 {
+			left, op, right = s[:i], candidate, s[i+len(candidate):]
+			break
+		}
+	}
+
+	left = strings.TrimSpace(left)
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter must start with @: %q", s)
+	}
+	steps, err := parseJPSteps(left[1:])
+	if err != nil {
+		return nil, err
+	}
+	f := &jpFilter{path: steps, op: op}
+	if op == "" {
+		return f, nil
+	}
+
+	right = strings.TrimSpace(right)
+	switch {
+	case strings.HasPrefix(right, "'") || strings.HasPrefix(right, `"`):
+		f.value, err = unquoteJP(right)
+	case right == "true", right == "false":
+		f.value = right == "true"
+	case right == "null":
+		f.value = nil
+	default:
+		var n float64
+		n, err = strconv.ParseFloat(right, 64)
+		f.value = n
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter value %q", right)
+	}
+	return f, nil
+}
+
+// indexUnquoted is strings.Index ignoring matches inside quotes.
+func indexUnquoted(s, sub string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(s[i:], sub):
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteJP unquotes a single- or double-quoted string.
+func unquoteJP(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), nil
+	}
+	return strconv.Unquote(s)
+}
+
+// Execute writes the template evaluated against the JSON encoding of data.
+func (p *JSONPath) Execute(w io.Writer, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := execJPNodes(&buf, p.nodes, root, root); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// execJPNodes evaluates nodes with cur as the current value.
+func execJPNodes(buf *bytes.Buffer, nodes []jpNode, root, cur interface{}) error {
+	for _, n := range nodes {
+		if !n.isVal {
+			buf.WriteString(n.text)
+			continue
+		}
+
+		start := cur
+		if n.root {
+			start = root
+		}
+		values := evalJPSteps(n.path, []interface{}{start})
+
+		if n.body != nil {
+			for _, v := range values {
+				if err := execJPNodes(buf, n.body, root, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		for i, v := range values {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			s, err := formatJPValue(v)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(s)
+		}
+	}
+	return nil
+}
+
+// evalJPSteps applies steps to each of values. Selectors that do not
+// match (missing fields, out-of-range indexes) yield nothing.
+func evalJPSteps(steps []jpStep, values []interface{}) []interface{} {
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range values {
+			next = append(next, evalJPStep(step, v)...)
+		}
+		values = next
+	}
+	return values
+}
+
+// evalJPStep applies one selector to v.
+func evalJPStep(step jpStep, v interface{}) []interface{} {
+	switch step.kind {
+	case jpField:
+		if m, ok := v.(map[string]interface{}); ok {
+			if child, ok := m[step.name]; ok {
+				return []interface{}{child}
+			}
+		}
+		return nil
+	case jpWildcard:
+		return jpChildren(v)
+	case jpRecursive:
+		var out []interface{}
+		var walk func(interface{})
+		walk = func(v interface{}) {
+			if m, ok := v.(map[string]interface{}); ok && step.name != "*" {
+				if child, ok := m[step.name]; ok {
+					out = append(out, child)
+				}
+			}
+			for _, child := range jpChildren(v) {
+				if step.name == "*" {
+					out = append(out, child)
+				}
+				walk(child)
+			}
+		}
+		walk(v)
+		return out
+	case jpIndex:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		i := step.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil
+		}
+		return []interface{}{list[i]}
+	case jpSlice:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		lo, hi := 0, len(list)
+		if step.start != nil {
+			lo = clampIndex(*step.start, len(list))
+		}
+		if step.end != nil {
+			hi = clampIndex(*step.end, len(list))
+		}
+		if lo >= hi {
+			return nil
+		}
+		return list[lo:hi]
+	case jpSelect:
+		var out []interface{}
+		for _, child := range jpChildren(v) {
+			if step.filter.match(child) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// jpChildren returns the elements of a list or the values of an object,
+// in key order.
+func jpChildren(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = v[k]
+		}
+		return out
+	}
+	return nil
+}
+
+// clampIndex resolves a possibly negative slice bound against length n.
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return min(max(i, 0), n)
+}
+
+// match reports whether v satisfies the filter.
+func (f *jpFilter) match(v interface{}) bool {
+	values := evalJPSteps(f.path, []interface{}{v})
+	if len(values) == 0 {
+		return false
+	}
+	got := values[0]
+
+	if f.op == "" {
+		return got != nil && got != false
+	}
+
+	if n, ok := got.(json.Number); ok {
+		want, ok := f.value.(float64)
+		if !ok {
+			return f.op == "!="
+		}
+		x, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		return compareOrdered(x, want, f.op)
+	}
+	if s, ok := got.(string); ok {
+		want, ok := f.value.(string)
+		if !ok {
+			return f.op == "!="
+		}
+		return compareOrdered(s, want, f.op)
+	}
+	switch f.op {
+	case "==":
+		return got == f.value
+	case "!=":
+		return got != f.value
+	}
+	return false
+}
+
+// compareOrdered applies a comparison operator to a and b.
+func compareOrdered[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// formatJPValue formats a selected value for output: strings and numbers
+// as-is, null as nothing, and objects and lists as compact JSON.
+func formatJPValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,95 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+// jsonPathData is the document the JSONPath tests select from.
+var jsonPathData = map[string]interface{}{
+	"total": 3,
+	"data": []interface{}{
+		map[string]interface{}{"id": "a1", "type": "sensors", "pins": 8, "active": true},
+		map[string]interface{}{"id": "b2", "type": "boards", "pins": 30, "active": false},
+		map[string]interface{}{"id": "c3", "type": "sensors", "pins": 4, "meta": map[string]interface{}{"id": "m3"}},
+	},
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		name, template, want string
+	}{
+		{"field", "{.total}", "3"},
+		{"root", "{$.total}", "3"},
+		{"literal text", "total={.total}\n", "total=3\n"},
+		{"quoted string", `{.total}{"\t"}x`, "3\tx"},
+		{"index", "{.data[0].id}", "a1"},
+		{"negative index", "{.data[-1].id}", "c3"},
+		{"index out of range", "{.data[5].id}", ""},
+		{"bracket field", "{.data[1]['id']}", "b2"},
+		{"wildcard", "{.data[*].id}", "a1 b2 c3"},
+		{"dot wildcard", "{.data[0].*}", "true a1 8 sensors"},
+		{"missing field", "{.data[*].meta.id}", "m3"},
+		{"object as JSON", "{.data[2].meta}", `{"id":"m3"}`},
+
+		{"slice", "{.data[0:2].id}", "a1 b2"},
+		{"slice open start", "{.data[:1].id}", "a1"},
+		{"slice open end", "{.data[1:].id}", "b2 c3"},
+		{"slice negative", "{.data[-2:].id}", "b2 c3"},
+		{"slice clamped", "{.data[1:10].id}", "b2 c3"},
+		{"slice empty", "{.data[2:1].id}", ""},
+
+		{"recursive field", "{..id}", "a1 b2 c3 m3"},
+		{"recursive below path", "{.data[2]..id}", "c3 m3"},
+
+		{"filter string", `{.data[?(@.type=="sensors")].id}`, "a1 c3"},
+		{"filter single quotes", `{.data[?(@.type=='boards')].id}`, "b2"},
+		{"filter not equal", `{.data[?(@.type!="sensors")].id}`, "b2"},
+		{"filter number", "{.data[?(@.pins>=8)].id}", "a1 b2"},
+		{"filter less", "{.data[?(@.pins<8)].id}", "c3"},
+		{"filter bool", "{.data[?(@.active==false)].id}", "b2"},
+		{"filter exists", "{.data[?(@.active)].id}", "a1"},
+		{"filter nested", `{.data[?(@.meta.id=="m3")].id}`, "c3"},
+		{"filter type mismatch", `{.data[?(@.pins=="8")].id}`, ""},
+		{"filter quoted operator", `{.data[?(@.type=="a>b")].id}`, ""},
+
+		{"range", `{range .data[*]}{.id}:{.pins}{"\n"}{end}`, "a1:8\nb2:30\nc3:4\n"},
+		{"range root", `{range .data[0:2]}{.id}={$.total} {end}`, "a1=3 b2=3 "},
+		{"range filter", `{range .data[?(@.type=="sensors")]}[{.id}]{end}`, "[a1][c3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("ParseJSONPath(%q): %v", tt.template, err)
+			}
+			var b strings.Builder
+			if err := p.Execute(&b, jsonPathData); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	tests := []string{
+		"{.data",
+		"{end}",
+		"{range .data[*]}{.id}",
+		"{.data[}",
+		"{.data[x]}",
+		"{.data[1:x]}",
+		"{..}",
+		"{.data[?(.type==1)]}",
+		"{.data[?(@.type==sensors)]}",
+		`{"unterminated}`,
+	}
+	for _, template := range tests {
+		if _, err := ParseJSONPath(template); err == nil {
+			t.Errorf("ParseJSONPath(%q) succeeded, want error", template)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package output
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxMarkdownWidth caps the width prose is wrapped to, for readability.
+const maxMarkdownWidth = 100
+
+// Markdown renders Markdown source for the terminal: headings are styled,
+// paragraphs and lists wrapped to the terminal width, tables aligned and
+// code blocks highlighted. Styles are only used when color is enabled.
+func (w *Writer) Markdown(src string) error {
+	width := w.width
+	if width <= 0 {
+		width = 80
+	}
+	r := &mdRenderer{w: w, width: min(width, maxMarkdownWidth)}
+	r.render(src)
+	_, err := io.WriteString(w.out, r.b.String())
+	return err
+}
+
+// IsTerminal returns true if stdout is a terminal.
+func (w *Writer) IsTerminal() bool {
+	return w.tty
+}
+
+// mdRenderer renders Markdown blocks into b.
+type mdRenderer struct {
+	w     *Writer
+	width int
+	b     strings.Builder
+}
+
+var (
+	mdHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule      = regexp.MustCompile(`^ {0,3}(?:(?:- *){3,}|(?:\* *){3,}|(?:_ *){3,})$`)
+	mdFence     = regexp.MustCompile("^( {0,3})(```+|~~~+)\\s*([^`\\s]*)")
+	mdListItem  = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdQuote     = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	mdTableSep  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdCodeSpan  = regexp.MustCompile("`+([^`]+)`+")
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdAutolink  = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	mdBold      = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdItalic    = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*|\b_(\S(?:[^_]*?\S)?)_\b`)
+	mdHardBreak = regexp.MustCompile(`( {2,}|\\)$`)
+)
+
+// render renders a sequence of blocks.
+func (r *mdRenderer) render(src string) {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	first := true
+	sep := func() {
+		if !first {
+			r.b.WriteByte('\n')
+		}
+		first = false
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFence.MatchString(line):
+			sep()
+			i = r.codeBlock(lines, i)
+
+		case mdHeading.MatchString(line):
+			sep()
+			m := mdHeading.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+
+		case mdRule.MatchString(line):
+			sep()
+			r.b.WriteString(r.w.style(strings.Repeat("─", r.width), ansiDim) + "\n")
+			i++
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && mdTableSep.MatchString(lines[i+1]):
+			sep()
+			i = r.table(lines, i)
+
+		case mdQuote.MatchString(line):
+			sep()
+			var quoted []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			inner := &mdRenderer{w: r.w, width: max(r.width-2, 20)}
+			inner.render(strings.Join(quoted, "\n"))
+			bar := r.w.style("│", ansiDim)
+			for _, l := range strings.Split(strings.TrimRight(inner.b.String(), "\n"), "\n") {
+				r.b.WriteString(strings.TrimRight(bar+" "+l, " ") + "\n")
+			}
+
+		case mdListItem.MatchString(line):
+			sep()
+			i = r.list(lines, i)
+
+		default:
+			sep()
+			var para []string
+			for ; i < len(lines) && !r.startsBlock(lines, i); i++ {
+				para = append(para, lines[i])
+			}
+			r.wrap(r.paragraph(para), "", "")
+		}
+	}
+}
+
+// startsBlock reports whether lines[i] ends a paragraph.
+func (r *mdRenderer) startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return strings.TrimSpace(line) == "" ||
+		mdFence.MatchString(line) ||
+		mdHeading.MatchString(line) ||
+		mdRule.MatchString(line) ||
+		mdQuote.MatchString(line) ||
+		mdListItem.MatchString(line) ||
+		(i+1 < len(lines) && strings.Contains(line, "|") && mdTableSep.MatchString(lines[i+1]))
+}
+
+// paragraph joins paragraph lines, keeping hard line breaks, and renders
+// inline markup. Line breaks are returned as "\n".
+func (r *mdRenderer) paragraph(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		hard := mdHardBreak.MatchString(l)
+		l = strings.TrimSpace(mdHardBreak.ReplaceAllString(l, ""))
+		b.WriteString(l)
+		if i < len(lines)-1 {
+			if hard {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+	}
+	return r.inline(b.String())
+}
+
+// heading writes a heading of the given level.
+func (r *mdRenderer) heading(level int, text string) {
+	text = r.inline(text)
+	if !r.w.color {
+		r.b.WriteString(strings.Repeat("#", level) + " " + text + "\n")
+		return
+	}
+	switch level {
+	case 1:
+		r.b.WriteString(ansiBold + ansiUnderline + ansiMagenta + text + ansiReset + "\n")
+	case 2:
+		r.b.WriteString(ansiBold + ansiMagenta + text + ansiReset + "\n")
+	default:
+		r.b.WriteString(ansiBold + strings.Repeat("#", level) + " " + text + ansiReset + "\n")
+	}
+}
+
+// wrap writes text word-wrapped to the width, starting the first line
+// with first and the others with rest.
+func (r *mdRenderer) wrap(text, first, rest string) {
+	r.b.WriteString(Wrap(text, r.width, first, rest) + "\n")
+}
+
+// list renders a list starting at lines[i], with nested items indented by
+// their source indentation, and returns the index after it.
+func (r *mdRenderer) list(lines []string, i int) int {
+	for i < len(lines) {
+		m := mdListItem.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    ")) / 2
+		text := []string{m[3]}
+		i++
+
+		// Continuation lines are indented and not new items or blocks.
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" &&
+			!mdListItem.MatchString(lines[i]) && !mdFence.MatchString(lines[i]) &&
+			(strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "\t") || !r.startsBlock(lines, i)) {
+			text = append(text, lines[i])
+			i++
+		}
+
+		marker := m[2]
+		if marker == "-" || marker == "*" || marker == "+" {
+			marker = "•"
+		}
+		body := strings.Join(text, "\n")
+		checked := ""
+		switch {
+		case strings.HasPrefix(body, "[ ] "):
+			checked, body = "☐ ", body[4:]
+		case strings.HasPrefix(body, "[x] "), strings.HasPrefix(body, "[X] "):
+			checked, body = "☑ ", body[4:]
+		}
+
+		pad := strings.Repeat("  ", indent)
+		first := pad + r.w.style(marker, ansiCyan) + " " + checked
+		rest := pad + strings.Repeat(" ", DisplayWidth(marker)+1+DisplayWidth(checked))
+		r.wrap(r.paragraph(strings.Split(body, "\n")), first, rest)
+
+		// A blank line followed by another item keeps the list going.
+		if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" && mdListItem.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+	return i
+}
+
+// codeBlock renders a fenced code block starting at lines[i] and returns
+// the index after its closing fence.
+func (r *mdRenderer) codeBlock(lines []string, i int) int {
+	m := mdFence.FindStringSubmatch(lines[i])
+	indent, fence, lang := len(m[1]), m[2], strings.ToLower(m[3])
+	i++
+
+	var code []string
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimLeft(lines[i], " "), fence) {
+			i++
+			break
+		}
+		l := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(l, " "); n++ {
+			l = l[1:]
+		}
+		code = append(code, l)
+	}
+
+	if lang != "" {
+		r.b.WriteString("  " + r.w.style(lang, ansiDim) + "\n")
+	}
+	for _, l := range code {
+		r.b.WriteString(strings.TrimRight("    "+r.highlight(strings.ReplaceAll(l, "\t", "    "), lang), " ") + "\n")
+	}
+	return i
+}
+
+// table renders a pipe table starting at lines[i] (the header row) and
+// returns the index after it.
+func (r *mdRenderer) table(lines []string, i int) int {
+	header := splitTableRow(lines[i])
+	aligns := splitTableRow(lines[i+1])
+	i += 2
+
+	var rows [][]string
+	for ; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+		rows = append(rows, splitTableRow(lines[i]))
+	}
+
+	cols := len(header)
+	all := append([][]string{header}, rows...)
+	widths := make([]int, cols)
+	for ri, row := range all {
+		for c := range cols {
+			cell := ""
+			if c < len(row) {
+				cell = r.inline(row[c])
+			}
+			all[ri] = padRow(all[ri], cols)
+			all[ri][c] = cell
+			widths[c] = max(widths[c], DisplayWidth(cell))
+		}
+	}
+	widths = fitWidths(widths, r.width)
+
+	for ri, row := range all {
+		var b strings.Builder
+		for c, cell := range row {
+			cell = Truncate(cell, widths[c])
+			gap := widths[c] - DisplayWidth(cell)
+			align := ""
+			if c < len(aligns) {
+				align = strings.TrimSpace(aligns[c])
+			}
+			var left, right int
+			switch {
+			case strings.HasPrefix(align, ":") && strings.HasSuffix(align, ":"):
+				left = gap / 2
+				right = gap - left
+			case strings.HasSuffix(align, ":"):
+				left = gap
+			default:
+				right = gap
+			}
+			if ri == 0 {
+				cell = r.w.style(cell, ansiBold)
+			}
+			b.WriteString(strings.Repeat(" ", left) + cell)
+			if c < cols-1 {
+				b.WriteString(strings.Repeat(" ", right+2))
+			}
+		}
+		r.b.WriteString(strings.TrimRight(b.String(), " ") + "\n")
+		if ri == 0 {
+			r.b.WriteString(r.w.style(strings.Repeat("─", ruleWidth(widths)), ansiDim) + "\n")
+		}
+	}
+	return i
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honoring
+// escaped pipes.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// padRow extends row to n cells.
+func padRow(row []string, n int) []string {
+	for len(row) < n {
+		row = append(row, "")
+	}
+	return row
+}
+
+// inline renders inline markup: code spans, links, images, bold and
+// italic text. Without color, emphasis markers are dropped and code spans
+// keep their backticks.
+func (r *mdRenderer) inline(s string) string {
+	// Render code spans separately so their contents stay literal.
+	var b strings.Builder
+	last := 0
+	for _, loc := range mdCodeSpan.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(r.inlineText(s[last:loc[0]]))
+		code := s[loc[2]:loc[3]]
+		if r.w.color {
+			b.WriteString(ansiCyan + code + ansiReset)
+		} else {
+			b.WriteString("`" + code + "`")
+		}
+		last = loc[1]
+	}
+	b.WriteString(r.inlineText(s[last:]))
+	return b.String()
+}
+
+// inlineText renders inline markup other than code spans.
+func (r *mdRenderer) inlineText(s string) string {
+	s = mdImage.ReplaceAllString(s, "[image: $1]")
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		if sub[1] == sub[2] {
+			return r.w.style(sub[1], ansiUnderline)
+		}
+		return r.w.style(sub[1], ansiUnderline) + " " + r.w.style("("+sub[2]+")", ansiDim)
+	})
+	s = mdAutolink.ReplaceAllStringFunc(s, func(m string) string {
+		return r.w.style(m[1:len(m)-1], ansiUnderline)
+	})
+	s = mdBold.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdBold.FindStringSubmatch(m)
+		return r.w.style(sub[1]+sub[2], ansiBold)
+	})
+	s = mdItalic.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdItalic.FindStringSubmatch(m)
+		return r.w.style(sub[1]+sub[2], ansiItalic)
+	})
+	return s
+}
+
+// codeKeywords are highlighted in code blocks of any language.
+var codeKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		if else elif for while do switch case default break continue return goto
+		func def fn function class struct enum union interface type typedef impl trait
+		import from package use include define ifdef ifndef endif pragma module export
+		const let var static extern volatile unsigned signed void int char short long
+		float double bool auto mut pub async await try catch except finally raise throw
+		new delete in is not and or with as lambda yield go defer select chan map range
+		true false nil null NULL None True False this self super`) {
+		codeKeywords[k] = true
+	}
+}
+
+// hashCommentLangs use # for line comments.
+var hashCommentLangs = map[string]bool{
+	"sh": true, "bash": true, "shell": true, "zsh": true, "console": true,
+	"python": true, "py": true, "ruby": true, "rb": true, "perl": true,
+	"yaml": true, "yml": true, "toml": true, "ini": true, "conf": true,
+	"make": true, "makefile": true, "cmake": true, "dockerfile": true,
+}
+
+// highlight colors comments, strings, numbers and keywords in a line of
+// code. Code in unknown or unnamed languages is only colored as a whole.
+func (r *mdRenderer) highlight(line, lang string) string {
+	if !r.w.color {
+		return line
+	}
+	if lang == "" || lang == "text" || lang == "txt" || lang == "plain" {
+		return ansiCyan + line + ansiReset
+	}
+
+	var b strings.Builder
+	rs := []rune(line)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case (c == '/' && i+1 < len(rs) && rs[i+1] == '/' && !hashCommentLangs[lang]) ||
+			(c == '#' && hashCommentLangs[lang]):
+			b.WriteString(ansiDim + string(rs[i:]) + ansiReset)
+			return b.String()
+
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(rs) && rs[j] != c {
+				if rs[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(rs))
+			b.WriteString(ansiGreen + string(rs[i:j]) + ansiReset)
+			i = j
+
+		case unicode.IsDigit(c) && (i == 0 || !isIdentRune(rs[i-1])):
+			j := i
+			for j < len(rs) && (isIdentRune(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			word := string(rs[i:j])
+			if _, err := strconv.ParseFloat(strings.TrimRight(word, "fFuUlL"), 64); err == nil || strings.HasPrefix(word, "0x") {
+				b.WriteString(ansiYellow + word + ansiReset)
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+
+		case isIdentRune(c):
+			j := i
+			for j < len(rs) && isIdentRune(rs[j]) {
+				j++
+			}
+			word := string(rs[i:j])
+			if codeKeywords[word] {
+				b.WriteString(ansiMagenta + word + ansiReset)
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+
+		default:
+			b.WriteRune(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isIdentRune reports whether c can be part of an identifier.
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,175 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+// renderMarkdown renders src as Markdown width columns wide.
+func renderMarkdown(t *testing.T, src string, width int, color bool) string {
+	t.Helper()
+	var b strings.Builder
+	w := &Writer{format: FormatTable, out: &b, width: width, color: color}
+	if err := w.Markdown(src); err != nil {
+		t.Fatalf("Markdown: %v", err)
+	}
+	return b.String()
+}
+
+func TestMarkdownTables(t *testing.T) {
+	tests := []struct {
+		name, src string
+		width     int
+		want      string
+	}{
+		{
+			name: "aligned",
+			src: "| Pin | Function | Volts |\n" +
+				"|:---|:---:|---:|\n" +
+				"| 1 | GND | 0 |\n" +
+				"| 23 | `VCC` | 3.3 |\n",
+			width: 80,
+			want: "Pin  Function  Volts\n" +
+				"────────────────────\n" +
+				"1      GND         0\n" +
+				"23    `VCC`      3.3\n",
+		},
+		{
+			name: "no outer pipes",
+			src: "a | b\n" +
+				"--|--\n" +
+				"x | y\n",
+			width: 80,
+			want: "a  b\n" +
+				"────\n" +
+				"x  y\n",
+		},
+		{
+			name: "escaped pipe and missing cell",
+			src: "| expr | note |\n" +
+				"|---|---|\n" +
+				"| a \\| b |\n",
+			width: 80,
+			want: "expr   note\n" +
+				"───────────\n" +
+				"a | b\n",
+		},
+		{
+			name: "inline markup",
+			src: "| name | link |\n" +
+				"|---|---|\n" +
+				"| **bold** | [docs](https://x.io) |\n",
+			width: 80,
+			want: "name  link\n" +
+				"─────────────────────────\n" +
+				"bold  docs (https://x.io)\n",
+		},
+		{
+			name: "wide characters",
+			src: "| 名前 | x |\n" +
+				"|---|---|\n" +
+				"| 日本語 | y |\n",
+			width: 80,
+			want: "名前    x\n" +
+				"─────────\n" +
+				"日本語  y\n",
+		},
+		{
+			name: "ends at blank line",
+			src: "| a |\n" +
+				"|---|\n" +
+				"| b |\n" +
+				"\n" +
+				"after\n",
+			width: 80,
+			want: "a\n" +
+				"─\n" +
+				"b\n" +
+				"\n" +
+				"after\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(t, tt.src, tt.width, false); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCodeFences(t *testing.T) {
+	tests := []struct {
+		name, src string
+		want      string
+	}{
+		{
+			name: "backticks with language",
+			src:  "```go\nfunc main() {\n\tx := 1\n}\n```\n",
+			want: "  go\n" +
+				"    func main() {\n" +
+				"        x := 1\n" +
+				"    }\n",
+		},
+		{
+			name: "tildes",
+			src:  "~~~\nplain text\n~~~\n",
+			want: "    plain text\n",
+		},
+		{
+			name: "markup kept literal",
+			src:  "```\n# not a heading\n| a | b |\n|---|---|\n**not bold**\n```\n",
+			want: "    # not a heading\n" +
+				"    | a | b |\n" +
+				"    |---|---|\n" +
+				"    **not bold**\n",
+		},
+		{
+			name: "longer closing fence",
+			src:  "````sh\n```\necho hi\n````\nafter\n",
+			want: "  sh\n" +
+				"    ```\n" +
+				"    echo hi\n" +
+				"\n" +
+				"after\n",
+		},
+		{
+			name: "indented fence",
+			src:  "  ```\n  indented\n    more\n  ```\n",
+			want: "    indented\n" +
+				"      more\n",
+		},
+		{
+			name: "unclosed",
+			src:  "```\nline one\nline two",
+			want: "    line one\n" +
+				"    line two\n",
+		},
+		{
+			name: "between paragraphs",
+			src:  "before\n```\ncode\n```\nafter\n",
+			want: "before\n" +
+				"\n" +
+				"    code\n" +
+				"\n" +
+				"after\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(t, tt.src, 80, false); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCodeHighlight(t *testing.T) {
+	got := renderMarkdown(t, "```c\nreturn 42; // done\n```\n", 80, true)
+	want := "  " + ansiDim + "c" + ansiReset + "\n" +
+		"    " + ansiMagenta + "return" + ansiReset + " " + ansiYellow + "42" + ansiReset + "; " +
+		ansiDim + "// done" + ansiReset + "\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -2,42 +2,131 @@
 package output
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
-	"text/tabwriter"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/term"
 )
 
 // Format represents an output format.
 type Format string
 
 const (
-	FormatTable Format = "table"
-	FormatJSON  Format = "json"
-	FormatText  Format = "text"
+	FormatTable  Format = "table"
+	FormatWide   Format = "wide"
+	FormatJSON   Format = "json"
+	FormatText   Format = "text"
+	FormatYAML   Format = "yaml"
+	FormatCSV    Format = "csv"
+	FormatTSV    Format = "tsv"
+	FormatNDJSON Format = "ndjson"
+
+	// FormatMarkdown writes Markdown documents: tables become pipe tables
+	// and device content is emitted as is.
+	FormatMarkdown Format = "markdown"
+
+	// FormatTemplate and FormatJSONPath take an argument, as in
+	// template=<text>, template-file=<path> or jsonpath=<expression>.
+	FormatTemplate Format = "template"
+	FormatJSONPath Format = "jsonpath"
 )
 
+// Formats lists the supported output formats.
+var Formats = []Format{FormatTable, FormatWide, FormatJSON, FormatText, FormatYAML, FormatCSV, FormatTSV, FormatNDJSON, FormatMarkdown}
+
+// ShortIDLen is the length to which tables shorten IDs.
+const ShortIDLen = 8
+
+// ParseFormat parses an output format name. "yml" is accepted for yaml,
+// "jsonl" for ndjson and "md" for markdown.
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(s))
+	switch f {
+	case "yml":
+		return FormatYAML, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	case "md":
+		return FormatMarkdown, nil
+	}
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, known := range Formats {
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("unknown output format %q (use %s, template=..., template-file=... or jsonpath=...)", s, strings.Join(names, ", "))
+}
+
 // Writer handles formatted output.
 type Writer struct {
-	format Format
-	out    io.Writer
+	format   Format
+	out      io.Writer
+	tmpl     *template.Template
+	jsonPath *JSONPath
+	table    TableOptions
+
+	// tty is set if stdout is a terminal; width is the width tables are
+	// fitted to (from the terminal or COLUMNS), 0 if unknown.
+	tty    bool
+	width  int
+	height int
+	color  bool
+	pager  *pager
 }
 
-// New creates a new output writer.
+// New creates a new output writer. Unknown or invalid formats fall back
+// to table; use Parse to report them.
 func New(format string) *Writer {
-	f := Format(strings.ToLower(format))
-	switch f {
-	case FormatJSON, FormatText:
-		// valid
+	w, err := Parse(format)
+	if err != nil {
+		w = &Writer{format: FormatTable, out: os.Stdout}
+		w.detectTerminal()
+	}
+	return w
+}
+
+// Parse creates an output writer for a format spec: a format name, or
+// template=<text>, template-file=<path> or jsonpath=<expression>.
+//
+// Templates are Go text/templates executed against the typed values
+// (e.g. {{.Name}}), once per record for lists. JSONPath expressions
+// select from the JSON encoding of the whole response (e.g.
+// {.data[*].id}).
+func Parse(spec string) (*Writer, error) {
+	w := &Writer{out: os.Stdout}
+	w.detectTerminal()
+
+	kind, arg, hasArg := strings.Cut(spec, "=")
+	var err error
+	switch {
+	case hasArg && kind == "template":
+		w.format = FormatTemplate
+		w.tmpl, err = parseTemplate(arg)
+	case hasArg && kind == "template-file":
+		w.format = FormatTemplate
+		w.tmpl, err = parseTemplateFile(arg)
+	case hasArg && kind == "jsonpath":
+		w.format = FormatJSONPath
+		w.jsonPath, err = ParseJSONPath(arg)
 	default:
-		f = FormatTable
+		w.format, err = ParseFormat(spec)
 	}
-	return &Writer{
-		format: f,
-		out:    os.Stdout,
+	if err != nil {
+		return nil, err
 	}
+	return w, nil
 }
 
 // JSON outputs data as JSON.
@@ -47,20 +136,270 @@ func (w *Writer) JSON(data interface{}) error {
 	return enc.Encode(data)
 }
 
-// Table outputs data as a table.
+// detectTerminal records whether stdout is a terminal and its size, and
+// enables color as for SetColor("auto").
+func (w *Writer) detectTerminal() {
+	fd := int(os.Stdout.Fd())
+	if w.tty = term.IsTerminal(fd); w.tty {
+		w.width, w.height, _ = term.GetSize(fd)
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		w.width = n
+	}
+	_ = w.SetColor(ColorAuto)
+}
+
+// StartPager sends subsequent output through a pager once it exceeds the
+// terminal height. It does nothing unless stdout is a terminal. command
+// is run by the shell; if empty, $PAGER is used, or else "less".
+func (w *Writer) StartPager(command string) {
+	if !w.tty || w.pager != nil {
+		return
+	}
+	if command == "" {
+		command = os.Getenv("PAGER")
+	}
+	if command == "" {
+		command = "less"
+	}
+	height := w.height
+	if height <= 0 {
+		height = 24
+	}
+	w.pager = &pager{command: command, height: height - 1, out: w.out}
+	w.out = w.pager
+}
+
+// Close finishes output: buffered output is written, or the pager is
+// waited for.
+func (w *Writer) Close() error {
+	if w.pager == nil {
+		return nil
+	}
+	p := w.pager
+	w.out, w.pager = p.out, nil
+	return p.Close()
+}
+
+// Value outputs a single document in the structured output format: indented
+// JSON, YAML, one line of NDJSON, or the template or JSONPath result.
+func (w *Writer) Value(data interface{}) error {
+	switch w.format {
+	case FormatTemplate, FormatJSONPath:
+		return w.execute(data)
+	case FormatYAML:
+		return w.YAML(data)
+	case FormatNDJSON:
+		return json.NewEncoder(w.out).Encode(data)
+	default:
+		return w.JSON(data)
+	}
+}
+
+// List outputs a list in the structured output format. JSON, YAML and
+// JSONPath use page, the full response including any totals; NDJSON and
+// templates use each element of records, a slice, so the output can be
+// processed record by record.
+func (w *Writer) List(page, records interface{}) error {
+	switch w.format {
+	case FormatTemplate:
+		return w.executeEach(records)
+	case FormatNDJSON:
+		// below
+	default:
+		return w.Value(page)
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, err := fmt.Fprintf(w.out, "%s\n", item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// YAML outputs data as YAML. Keys and field order follow the JSON encoding
+// of data.
+func (w *Writer) YAML(data interface{}) error {
+	node, err := yamlNode(data)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w.out)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlNode converts data to a YAML node through its JSON encoding, so the
+// json struct tags apply, and switches it to block style.
+func yamlNode(data interface{}) (*yaml.Node, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var unstyle func(n *yaml.Node)
+	unstyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			unstyle(c)
+		}
+	}
+	unstyle(&doc)
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		return doc.Content[0], nil
+	}
+	return &doc, nil
+}
+
+// Table outputs data as a table, as delimited rows with a header line in
+// the csv and tsv formats, or as a Markdown pipe table. The header line is
+// omitted if the table options say so, except from Markdown tables, which
+// require it.
 func (w *Writer) Table(headers []string, rows [][]string) {
-	tw := tabwriter.NewWriter(w.out, 0, 0, 2, ' ', 0)
-	
+	if w.IsDelimited() {
+		if !w.table.NoHeaders {
+			rows = append([][]string{headers}, rows...)
+		}
+		_ = w.delimited(rows)
+		return
+	}
+	if w.format == FormatMarkdown {
+		w.markdownTable(headers, rows)
+		return
+	}
+
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], DisplayWidth(cell))
+			}
+		}
+	}
+	widths = fitWidths(widths, w.width)
+
 	// Print headers
-	fmt.Fprintln(tw, strings.Join(headers, "\t"))
-	fmt.Fprintln(tw, strings.Repeat("-", len(strings.Join(headers, "  "))))
-	
+	if !w.table.NoHeaders {
+		w.writeRow(headers, widths, true)
+		fmt.Fprintln(w.out, strings.Repeat("-", ruleWidth(widths)))
+	}
+
 	// Print rows
 	for _, row := range rows {
-		fmt.Fprintln(tw, strings.Join(row, "\t"))
+		w.writeRow(row, widths, false)
+	}
+}
+
+// writeRow writes a table row with cells truncated or padded to widths
+// and separated by two spaces. Header cells are bold when color is on.
+func (w *Writer) writeRow(row []string, widths []int, header bool) {
+	var b strings.Builder
+	for i, cell := range row {
+		pad := 0
+		if i < len(widths) {
+			cell = Truncate(cell, widths[i])
+			pad = widths[i] - DisplayWidth(cell)
+		}
+		if header {
+			cell = w.style(cell, ansiBold)
+		}
+		b.WriteString(cell)
+		if i < len(row)-1 {
+			b.WriteString(strings.Repeat(" ", max(pad, 0)+2))
+		}
 	}
-	
-	tw.Flush()
+	fmt.Fprintln(w.out, strings.TrimRight(b.String(), " "))
+}
+
+// ruleWidth returns the width of the line under table headers.
+func ruleWidth(widths []int) int {
+	total := 0
+	for _, w := range widths {
+		total += w + 2
+	}
+	return max(total-2, 0)
+}
+
+// delimited writes rows as CSV or TSV. TSV cells have tabs and line breaks
+// replaced by spaces, as the format has no quoting.
+func (w *Writer) delimited(rows [][]string) error {
+	if w.format == FormatTSV {
+		var b bytes.Buffer
+		clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+		for _, row := range rows {
+			for i, cell := range row {
+				if i > 0 {
+					b.WriteByte('\t')
+				}
+				b.WriteString(clean.Replace(cell))
+			}
+			b.WriteByte('\n')
+		}
+		_, err := w.out.Write(b.Bytes())
+		return err
+	}
+
+	cw := csv.NewWriter(w.out)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// markdownTable writes a Markdown pipe table.
+func (w *Writer) markdownTable(headers []string, rows [][]string) {
+	rule := make([]string, len(headers))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	w.markdownRows(append([][]string{headers, rule}, rows...))
+}
+
+// markdownRows writes rows of a Markdown pipe table, escaping pipes and
+// line breaks in cells.
+func (w *Writer) markdownRows(rows [][]string) {
+	escape := strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = escape.Replace(cell)
+		}
+		fmt.Fprintf(w.out, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// MarkdownDocument writes a Markdown document: fields as YAML front matter,
+// then body.
+func (w *Writer) MarkdownDocument(fields interface{}, body string) error {
+	if _, err := fmt.Fprintln(w.out, "---"); err != nil {
+		return err
+	}
+	if err := w.YAML(fields); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w.out, "---"); err != nil {
+		return err
+	}
+	if body = strings.TrimSpace(body); body == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w.out, "\n%s\n", body)
+	return err
 }
 
 // Text outputs plain text.
@@ -73,6 +412,12 @@ func (w *Writer) Println(args ...interface{}) {
 	fmt.Fprintln(w.out, args...)
 }
 
+// Wrap word-wraps text to the width tables are fitted to, with every line
+// indented by indent. If the width is unknown, text is only indented.
+func (w *Writer) Wrap(text, indent string) string {
+	return Wrap(text, w.width, indent, indent)
+}
+
 // Format returns the current output format.
 func (w *Writer) Format() Format {
 	return w.format
@@ -83,15 +428,37 @@ func (w *Writer) IsJSON() bool {
 	return w.format == FormatJSON
 }
 
-// Truncate truncates a string to a maximum length.
-func Truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// IsStructured returns true if the output format encodes values rather
+// than rows: json, yaml, ndjson, template or jsonpath. Such output is
+// written with Value or List.
+func (w *Writer) IsStructured() bool {
+	switch w.format {
+	case FormatJSON, FormatYAML, FormatNDJSON, FormatTemplate, FormatJSONPath:
+		return true
 	}
-	if maxLen <= 3 {
-		return s[:maxLen]
+	return false
+}
+
+// IsDelimited returns true if the output format is csv or tsv. Tables are
+// then written as delimited rows and other text should be omitted.
+func (w *Writer) IsDelimited() bool {
+	return w.format == FormatCSV || w.format == FormatTSV
+}
+
+// IsHuman returns true if the output format is table, wide, text or
+// markdown, meant to be read rather than parsed. Only such output
+// includes headings, counts and hints.
+func (w *Writer) IsHuman() bool {
+	return !w.IsStructured() && !w.IsDelimited()
+}
+
+// ShortID returns id shortened for display in a table. Delimited output
+// keeps the full ID.
+func (w *Writer) ShortID(id string) string {
+	if w.IsDelimited() || len(id) <= ShortIDLen {
+		return id
 	}
-	return s[:maxLen-3] + "..."
+	return id[:ShortIDLen]
 }
 
 // FormatSize formats a byte size as a human-readable string.
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// pager buffers output until it no longer fits on the screen, then starts
+// a pager command and streams everything through it. Short output is
+// written directly when the pager is closed.
+type pager struct {
+	command string
+	height  int
+	out     io.Writer
+
+	buf   bytes.Buffer
+	lines int
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  bool // output goes directly to out, or the pager has exited
+}
+
+// Write implements io.Writer.
+func (p *pager) Write(b []byte) (int, error) {
+	switch {
+	case p.stdin != nil:
+		if _, err := p.stdin.Write(b); err != nil {
+			// The user quit the pager; discard the rest.
+			p.stdin.Close()
+			p.stdin = nil
+			p.done = true
+		}
+		return len(b), nil
+	case p.done:
+		if p.cmd != nil {
+			return len(b), nil
+		}
+		return p.out.Write(b)
+	}
+
+	p.buf.Write(b)
+	p.lines += bytes.Count(b, []byte("\n"))
+	if p.lines < p.height {
+		return len(b), nil
+	}
+
+	if err := p.start(); err != nil {
+		// Without a working pager, write directly.
+		p.done = true
+		if _, err := p.out.Write(p.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		p.buf.Reset()
+		return len(b), nil
+	}
+	if _, err := p.stdin.Write(p.buf.Bytes()); err != nil {
+		p.stdin.Close()
+		p.stdin = nil
+		p.done = true
+	}
+	p.buf.Reset()
+	return len(b), nil
+}
+
+// start runs the pager command through the shell.
+func (p *pager) start() error {
+	if runtime.GOOS == "windows" {
+		p.cmd = exec.Command("cmd", "/C", p.command)
+	} else {
+		p.cmd = exec.Command("sh", "-c", p.command)
+	}
+	p.cmd.Stdout = p.out
+	p.cmd.Stderr = os.Stderr
+	p.cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// Quit if one screen, pass colors through, keep the screen.
+		p.cmd.Env = append(p.cmd.Env, "LESS=FRX")
+	}
+
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := p.cmd.Start(); err != nil {
+		p.cmd = nil
+		return err
+	}
+	p.stdin = stdin
+	return nil
+}
+
+// Close writes buffered output, or waits for the pager to exit.
+func (p *pager) Close() error {
+	if p.cmd == nil {
+		_, err := p.out.Write(p.buf.Bytes())
+		p.buf.Reset()
+		return err
+	}
+	if p.stdin != nil {
+		p.stdin.Close()
+		p.stdin = nil
+	}
+	err := p.cmd.Wait()
+	p.cmd = nil
+	p.done = true
+	if _, ok := err.(*exec.ExitError); ok {
+		// The pager's exit status (e.g. after being quit) is not ours.
+		return nil
+	}
+	return err
+}
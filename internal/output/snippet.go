@@ -0,0 +1,156 @@
+package output
+
+import (
+	"strings"
+	"unicode"
+)
+
+// excerptSnap is how far, in runes, Excerpt moves a cut to fall between
+// words.
+const excerptSnap = 16
+
+// Excerpt returns about maxLen terminal columns of text around the first
+// occurrence of any of terms, with whitespace collapsed and "..." marking
+// the ends that were cut. The window starts a quarter of maxLen before the
+// match, so it is shown with context; cuts fall between words where one is
+// near. A maxLen of 0 or less returns the whole text.
+func Excerpt(text string, terms []string, maxLen int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if maxLen <= 0 || DisplayWidth(text) <= maxLen {
+		return text
+	}
+	runes := []rune(text)
+
+	start := 0
+	if at := matchIndex(runes, terms); at >= 0 {
+		start = max(at-maxLen/4, 0)
+	}
+	if start > 0 {
+		for i := start; i < min(start+excerptSnap, len(runes)); i++ {
+			if runes[i] == ' ' {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	// Fill the window, leaving room for the ellipses.
+	budget := maxLen
+	if start > 0 {
+		budget -= len(ellipsis)
+	}
+	end, used := start, 0
+	for end < len(runes) && used+runeWidth(runes[end]) <= budget {
+		used += runeWidth(runes[end])
+		end++
+	}
+	if end < len(runes) {
+		for used+len(ellipsis) > budget && end > start {
+			end--
+			used -= runeWidth(runes[end])
+		}
+		for i := end; i > max(end-excerptSnap, start); i-- {
+			if runes[i-1] == ' ' {
+				end = i - 1
+				break
+			}
+		}
+	}
+
+	s := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		s = ellipsis + s
+	}
+	if end < len(runes) {
+		s += ellipsis
+	}
+	return s
+}
+
+// matchIndex returns the rune index of the first occurrence of any of terms
+// in runes, ignoring case, or -1.
+func matchIndex(runes []rune, terms []string) int {
+	lower := []rune(strings.ToLower(string(runes)))
+	if len(lower) != len(runes) {
+		// Lowercasing changed the length; match without folding.
+		lower = runes
+	}
+	best := -1
+	for _, t := range terms {
+		if at := runeIndex(lower, []rune(strings.ToLower(t))); at >= 0 && (best < 0 || at < best) {
+			best = at
+		}
+	}
+	return best
+}
+
+// runeIndex returns the index of the first occurrence of sub in s, or -1.
+func runeIndex(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Highlight marks every occurrence of terms in text, ignoring case: in
+// bold yellow when color is on, and in bold (**term**) in Markdown output.
+// Otherwise text is returned as is. Only occurrences that start a word are
+// marked, so "pin" highlights "pins" but not "spin".
+func (w *Writer) Highlight(text string, terms []string) string {
+	var open, close string
+	switch {
+	case w.format == FormatMarkdown:
+		open, close = "**", "**"
+	case w.color:
+		open, close = ansiBold+ansiYellow, ansiReset
+	default:
+		return text
+	}
+
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+	var lowTerms [][]rune
+	for _, t := range terms {
+		if t != "" {
+			lowTerms = append(lowTerms, []rune(strings.ToLower(t)))
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		n := 0
+		if i == 0 || !isWordRune(runes[i-1]) {
+			for _, t := range lowTerms {
+				if len(t) > n && i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == string(t) {
+					n = len(t)
+				}
+			}
+		}
+		if n == 0 {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		// Extend the match to the end of the word.
+		end := i + n
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		b.WriteString(open + string(runes[i:end]) + close)
+		i = end
+	}
+	return b.String()
+}
+
+// isWordRune reports whether r is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
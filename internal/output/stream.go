@@ -0,0 +1,234 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Stream writes a list of records incrementally, so long listings can be
+// printed page by page without holding every record in memory.
+//
+// JSON and YAML output have the same shape as a single list page:
+// {"data": [...], "total": N}. NDJSON output is one record per line, and
+// templates are executed for each record as it is added. JSONPath needs
+// the whole list, so records are collected and evaluated on Close. Table
+// output is written on each Flush; columns are padded to the widest value
+// seen so far. CSV, TSV and Markdown output is written on each Flush
+// without padding. Sorted tables (TableOptions.SortBy) are held until
+// Close.
+type Stream struct {
+	w       *Writer
+	table   *recordTable
+	headers []string
+	widths  []int
+	rows    []tableRow
+	records []interface{}
+	count   int
+	started bool
+}
+
+// Stream starts a streamed listing with the given table columns, which
+// are chosen and sorted as for Records.
+func (w *Writer) Stream(cols []Column) *Stream {
+	t := w.newRecordTable(cols)
+	headers := t.headers()
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = DisplayWidth(h)
+	}
+	return &Stream{
+		w:       w,
+		table:   t,
+		headers: headers,
+		widths:  widths,
+	}
+}
+
+// Add writes a record. Structured output encodes record immediately; table
+// and delimited output buffer its row until the next Flush.
+func (s *Stream) Add(record interface{}) error {
+	s.count++
+
+	switch s.w.format {
+	case FormatNDJSON:
+		return json.NewEncoder(s.w.out).Encode(record)
+	case FormatYAML:
+		return s.addYAML(record)
+	case FormatTemplate:
+		return s.w.execute(record)
+	case FormatJSONPath:
+		s.records = append(s.records, record)
+		return nil
+	case FormatJSON:
+		// below
+	default:
+		row, err := s.table.row(record)
+		if err != nil {
+			return err
+		}
+		s.rows = append(s.rows, row)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(record, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !s.started {
+		sep = "{\n  \"data\": [\n"
+		s.started = true
+	}
+	_, err = fmt.Fprintf(s.w.out, "%s    %s", sep, data)
+	return err
+}
+
+// addYAML writes record as an item of the YAML data sequence.
+func (s *Stream) addYAML(record interface{}) error {
+	node, err := yamlNode(record)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{node}}); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		if _, err := fmt.Fprintln(s.w.out, "data:"); err != nil {
+			return err
+		}
+		s.started = true
+	}
+	// Indent the item under "data:" as Writer.YAML would.
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := fmt.Fprint(s.w.out, "  "+line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered table rows, printing the headers first if needed.
+// Sorted tables are not written until Close.
+func (s *Stream) Flush() {
+	if s.table.sortCol != nil {
+		return
+	}
+	s.flush()
+}
+
+// flush writes buffered table rows.
+func (s *Stream) flush() {
+	if s.w.IsStructured() || len(s.rows) == 0 {
+		return
+	}
+
+	if s.w.IsDelimited() {
+		rows := make([][]string, 0, len(s.rows)+1)
+		if !s.started && !s.w.table.NoHeaders {
+			rows = append(rows, s.headers)
+		}
+		s.started = true
+		for _, row := range s.rows {
+			rows = append(rows, row.cells)
+		}
+		_ = s.w.delimited(rows)
+		s.rows = s.rows[:0]
+		return
+	}
+
+	if s.w.format == FormatMarkdown {
+		rows := make([][]string, len(s.rows))
+		for i, row := range s.rows {
+			rows[i] = row.cells
+		}
+		if s.started {
+			s.w.markdownRows(rows)
+		} else {
+			s.w.markdownTable(s.headers, rows)
+		}
+		s.started = true
+		s.rows = s.rows[:0]
+		return
+	}
+
+	for _, row := range s.rows {
+		for i, cell := range row.cells {
+			if i < len(s.widths) {
+				s.widths[i] = max(s.widths[i], DisplayWidth(cell))
+			}
+		}
+	}
+	widths := fitWidths(s.widths, s.w.width)
+
+	if !s.started && !s.w.table.NoHeaders {
+		s.w.writeRow(s.headers, widths, true)
+		fmt.Fprintln(s.w.out, strings.Repeat("-", ruleWidth(widths)))
+	}
+	s.started = true
+
+	for _, row := range s.rows {
+		s.w.writeRow(row.cells, widths, false)
+	}
+	s.rows = s.rows[:0]
+}
+
+// Close flushes remaining output and terminates the JSON or YAML document.
+func (s *Stream) Close() error {
+	switch s.w.format {
+	case FormatJSON:
+		// below
+	case FormatYAML:
+		if !s.started {
+			_, err := fmt.Fprintln(s.w.out, "data: []")
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(s.w.out, "total: %d\n", s.count)
+		return err
+	case FormatJSONPath:
+		records := s.records
+		if records == nil {
+			records = []interface{}{}
+		}
+		return s.w.execute(map[string]interface{}{"data": records, "total": s.count})
+	case FormatNDJSON, FormatTemplate:
+		return nil
+	default:
+		s.table.sort(s.rows)
+		s.flush()
+		if s.w.IsDelimited() && !s.started && !s.w.table.NoHeaders {
+			return s.w.delimited([][]string{s.headers})
+		}
+		if s.w.format == FormatMarkdown && !s.started {
+			s.w.markdownTable(s.headers, nil)
+		}
+		return nil
+	}
+
+	if !s.started {
+		_, err := fmt.Fprintf(s.w.out, "{\n  \"data\": [],\n  \"total\": 0\n}\n")
+		return err
+	}
+	_, err := fmt.Fprintf(s.w.out, "\n  ],\n  \"total\": %d\n}\n", s.count)
+	return err
+}
+
+// Count returns the number of records added so far.
+func (s *Stream) Count() int {
+	return s.count
+}
@@ -0,0 +1,269 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Column describes a column of a record table.
+type Column struct {
+	// Name is the column heading. TableOptions refer to columns by Name,
+	// in any case, or by Field.
+	Name string
+
+	// Field is the dotted path of the value in the JSON encoding of a
+	// record, such as "size_bytes" or "metadata.vendor".
+	Field string
+
+	// Format formats the value for table output; nil prints it as is.
+	// CSV and TSV output always use the unformatted value.
+	Format func(v interface{}) string
+
+	// Wide marks columns shown by default only with -o wide.
+	Wide bool
+}
+
+// TableOptions select and order the rows and columns of record tables.
+type TableOptions struct {
+	// Columns names the columns to show, in order. Names that match no
+	// column are taken as field paths, so any record field can be shown.
+	// Empty shows the default columns.
+	Columns []string
+
+	// SortBy names the column or field path to sort rows by. Numbers sort
+	// numerically, other values as strings.
+	SortBy string
+
+	// Reverse sorts in descending order.
+	Reverse bool
+
+	// NoHeaders omits the header line.
+	NoHeaders bool
+}
+
+// SetTableOptions sets the options for subsequent tables.
+func (w *Writer) SetTableOptions(opts TableOptions) {
+	w.table = opts
+}
+
+// ShortIDCell formats an ID column as tables do, shortened to ShortIDLen.
+func ShortIDCell(v interface{}) string {
+	s := cellText(v)
+	if len(s) <= ShortIDLen {
+		return s
+	}
+	return s[:ShortIDLen]
+}
+
+// TruncateCell returns a Column.Format that truncates values to maxLen.
+func TruncateCell(maxLen int) func(v interface{}) string {
+	return func(v interface{}) string {
+		return Truncate(cellText(v), maxLen)
+	}
+}
+
+// SizeCell formats a byte count column with FormatSize.
+func SizeCell(v interface{}) string {
+	n, ok := v.(json.Number)
+	if !ok {
+		return cellText(v)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return n.String()
+	}
+	return FormatSize(i)
+}
+
+// Records writes records, a slice, as a table of columns, applying the
+// table options. Delimited formats write CSV or TSV with the same
+// columns.
+func (w *Writer) Records(cols []Column, records interface{}) error {
+	t := w.newRecordTable(cols)
+
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("output: Records needs a slice, got %T", records)
+	}
+	rows := make([]tableRow, 0, v.Len())
+	for i := range v.Len() {
+		row, err := t.row(v.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	t.sort(rows)
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = row.cells
+	}
+	w.Table(t.headers(), cells)
+	return nil
+}
+
+// recordTable is the resolved layout of a record table.
+type recordTable struct {
+	w       *Writer
+	cols    []Column
+	sortCol *Column
+}
+
+// tableRow is a formatted row with the value it sorts by.
+type tableRow struct {
+	cells []string
+	key   interface{}
+}
+
+// newRecordTable resolves the columns to show and the sort column.
+func (w *Writer) newRecordTable(cols []Column) *recordTable {
+	t := &recordTable{w: w}
+
+	if len(w.table.Columns) > 0 {
+		for _, name := range w.table.Columns {
+			if name = strings.TrimSpace(name); name != "" {
+				t.cols = append(t.cols, lookupColumn(cols, name))
+			}
+		}
+	} else {
+		for _, c := range cols {
+			if !c.Wide || w.format == FormatWide {
+				t.cols = append(t.cols, c)
+			}
+		}
+	}
+
+	if w.table.SortBy != "" {
+		c := lookupColumn(cols, w.table.SortBy)
+		t.sortCol = &c
+	}
+	return t
+}
+
+// lookupColumn returns the column with the given name or field, or an
+// unformatted column for the field path name.
+func lookupColumn(cols []Column, name string) Column {
+	for _, c := range cols {
+		if strings.EqualFold(c.Name, name) || c.Field == name {
+			return c
+		}
+	}
+	return Column{Name: strings.ToUpper(name), Field: name}
+}
+
+// headers returns the column headings.
+func (t *recordTable) headers() []string {
+	headers := make([]string, len(t.cols))
+	for i, c := range t.cols {
+		headers[i] = c.Name
+	}
+	return headers
+}
+
+// row formats record.
+func (t *recordTable) row(record interface{}) (tableRow, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return tableRow{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return tableRow{}, err
+	}
+
+	row := tableRow{cells: make([]string, len(t.cols))}
+	for i, c := range t.cols {
+		v := lookupField(fields, c.Field)
+		if c.Format != nil && !t.w.IsDelimited() {
+			row.cells[i] = c.Format(v)
+		} else {
+			row.cells[i] = cellText(v)
+		}
+	}
+	if t.sortCol != nil {
+		row.key = lookupField(fields, t.sortCol.Field)
+	}
+	return row, nil
+}
+
+// sort orders rows by the sort column, if any.
+func (t *recordTable) sort(rows []tableRow) {
+	if t.sortCol == nil {
+		return
+	}
+	reverse := t.w.table.Reverse
+	sort.SliceStable(rows, func(i, j int) bool {
+		if reverse {
+			return compareValues(rows[j].key, rows[i].key) < 0
+		}
+		return compareValues(rows[i].key, rows[j].key) < 0
+	})
+}
+
+// lookupField returns the value at a dotted path in decoded JSON, or nil.
+func lookupField(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// cellText formats a decoded JSON value as a cell.
+func cellText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// compareValues orders decoded JSON values: missing values first, numbers
+// numerically, anything else by its text.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if an, ok := a.(json.Number); ok {
+		if bn, ok := b.(json.Number); ok {
+			x, errX := an.Float64()
+			y, errY := bn.Float64()
+			if errX == nil && errY == nil {
+				switch {
+				case x < y:
+					return -1
+				case x > y:
+					return 1
+				}
+				return 0
+			}
+		}
+	}
+	return strings.Compare(cellText(a), cellText(b))
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs are the functions available to output templates in
+// addition to the text/template builtins.
+var TemplateFuncs = template.FuncMap{
+	// size formats a byte count, e.g. {{size .SizeBytes}} -> "1.5 MB".
+	"size": func(v interface{}) (string, error) {
+		n, err := toInt64(v)
+		if err != nil {
+			return "", err
+		}
+		return FormatSize(n), nil
+	},
+	// short shortens an ID as tables do, e.g. {{short .ID}}.
+	"short": func(id string) string {
+		if len(id) <= ShortIDLen {
+			return id
+		}
+		return id[:ShortIDLen]
+	},
+	// truncate shortens a string, e.g. {{truncate 20 .Name}}.
+	"truncate": func(n int, s string) string {
+		return Truncate(s, n)
+	},
+	// join joins a list of strings, e.g. {{join ", " .Tags}}.
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+	// json encodes a value as compact JSON, e.g. {{json .Metadata}}.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseTemplate compiles an output template.
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// parseTemplateFile compiles the output template in the file at path.
+func parseTemplateFile(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return parseTemplate(string(text))
+}
+
+// execute writes data through the writer's template or JSONPath
+// expression, ending the output with a newline.
+func (w *Writer) execute(data interface{}) error {
+	var buf bytes.Buffer
+	var err error
+	if w.jsonPath != nil {
+		err = w.jsonPath.Execute(&buf, data)
+	} else {
+		err = w.tmpl.Execute(&buf, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to execute output template: %w", err)
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err = w.out.Write(buf.Bytes())
+	return err
+}
+
+// executeEach executes the template for each element of records, a slice.
+func (w *Writer) executeEach(records interface{}) error {
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		return w.execute(records)
+	}
+	for i := range v.Len() {
+		if err := w.execute(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toInt64 converts an integer or float value to int64.
+func toInt64(v interface{}) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return int64(rv.Float()), nil
+	}
+	return 0, fmt.Errorf("size: expected a number, got %T", v)
+}
@@ -0,0 +1,199 @@
+package output
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// ellipsis marks truncated text.
+const ellipsis = "..."
+
+// DisplayWidth returns the number of terminal columns s occupies. East
+// Asian wide characters count as two columns; combining marks, joiners
+// and ANSI escape sequences as none.
+func DisplayWidth(s string) int {
+	n := 0
+	for s != "" {
+		_, rest, w := nextCluster(s)
+		n += w
+		s = rest
+	}
+	return n
+}
+
+// Truncate shortens s to at most maxLen terminal columns, ending it with
+// "..." if it was shortened. It never splits a character from its
+// combining marks or an ANSI escape sequence; color is reset after
+// truncated colored text.
+func Truncate(s string, maxLen int) string {
+	if DisplayWidth(s) <= maxLen {
+		return s
+	}
+	target, suffix := maxLen-len(ellipsis), ellipsis
+	if maxLen <= len(ellipsis) {
+		target, suffix = maxLen, ""
+	}
+
+	var b strings.Builder
+	used, styled := 0, false
+	for s != "" {
+		cluster, rest, w := nextCluster(s)
+		if w == 0 && strings.HasPrefix(cluster, "\x1b") {
+			styled = true
+		} else if used+w > target {
+			break
+		}
+		b.WriteString(cluster)
+		used += w
+		s = rest
+	}
+	if styled {
+		b.WriteString(ansiReset)
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// Wrap word-wraps text to width terminal columns, starting the first line
+// with first and the others with rest. Line breaks in text are kept, and
+// words wider than the line are not broken. A width of 0 or less only
+// applies the prefixes.
+func Wrap(text string, width int, first, rest string) string {
+	var b strings.Builder
+	prefix := first
+	for i, hardLine := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		line := prefix
+		lineWidth := DisplayWidth(prefix)
+		empty := true
+		for _, word := range strings.Fields(hardLine) {
+			ww := DisplayWidth(word)
+			if !empty && width > 0 && lineWidth+1+ww > width {
+				b.WriteString(strings.TrimRight(line, " ") + "\n")
+				line, lineWidth, empty = rest, DisplayWidth(rest), true
+			}
+			if !empty {
+				line += " "
+				lineWidth++
+			}
+			line += word
+			lineWidth += ww
+			empty = false
+		}
+		b.WriteString(strings.TrimRight(line, " "))
+		prefix = rest
+	}
+	return b.String()
+}
+
+// nextCluster splits the first user-perceived character (or ANSI escape
+// sequence) off s and returns it, the rest, and its display width.
+func nextCluster(s string) (cluster, rest string, w int) {
+	if n := ansiLen(s); n > 0 {
+		return s[:n], s[n:], 0
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	w = runeWidth(r)
+	i := size
+	for i < len(s) {
+		next, nsize := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\u200d':
+			// A zero-width joiner joins the following character.
+		case isRegionalIndicator(r) && isRegionalIndicator(next) && i == size:
+			// A pair of regional indicators is one flag.
+			w = 2
+		case runeWidth(next) == 0 && !strings.HasPrefix(s[i:], "\x1b"):
+			// Combining marks, variation selectors and joiners.
+		case isEmojiModifier(next):
+			// Skin tone modifiers belong to the preceding emoji.
+		default:
+			return s[:i], s[i:], w
+		}
+		r = next
+		i += nsize
+	}
+	return s, "", w
+}
+
+// runeWidth returns the display width of a single rune.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x7f:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf), isVariationSelector(r):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+func isVariationSelector(r rune) bool {
+	return (r >= 0xfe00 && r <= 0xfe0f) || (r >= 0xe0100 && r <= 0xe01ef)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
+
+// ansiLen returns the length of the ANSI CSI escape sequence at the start
+// of s, or 0.
+func ansiLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// fitWidths shrinks column widths so that a table with two-space gaps fits
+// in total columns, narrowing the widest columns first but none below
+// minColumnWidth. A total of 0 or less means no limit.
+func fitWidths(widths []int, total int) []int {
+	fitted := append([]int(nil), widths...)
+	if total <= 0 || len(fitted) == 0 {
+		return fitted
+	}
+
+	avail := total - 2*(len(fitted)-1)
+	sum := 0
+	for _, w := range fitted {
+		sum += w
+	}
+	for sum > avail {
+		widest := 0
+		for i, w := range fitted {
+			if w > fitted[widest] {
+				widest = i
+			}
+		}
+		if fitted[widest] <= minColumnWidth {
+			break
+		}
+		fitted[widest]--
+		sum--
+	}
+	return fitted
+}
+
+// minColumnWidth is the narrowest a column is shrunk to fit the terminal.
+const minColumnWidth = 8
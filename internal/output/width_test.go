@@ -0,0 +1,86 @@
+package output
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name, s string
+		want    int
+	}{
+		{"empty", "", 0},
+		{"ascii", "hello", 5},
+		{"wide", "日本語", 6},
+		{"mixed", "a日b", 4},
+		{"combining mark", "été", 3},
+		{"skin tone", "👍🏽", 2},
+		{"flag", "🇯🇵", 2},
+		{"zwj sequence", "👨‍👩‍👧", 2},
+		{"variation selector", "☺️", 1},
+		{"ansi", "\x1b[31mred\x1b[0m", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DisplayWidth(tt.s); got != tt.want {
+				t.Errorf("DisplayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name, s string
+		maxLen  int
+		want    string
+	}{
+		{"fits", "hello", 5, "hello"},
+		{"ascii", "hello world", 8, "hello..."},
+		{"wide fits", "日本語", 6, "日本語"},
+		{"wide", "日本語テキスト", 7, "日本..."},
+		{"wide not split", "日本語", 5, "日..."},
+		{"wide after narrow", "a日本語", 6, "a日..."},
+		{"wide wider than room", "日本語テ", 4, "..."},
+		{"no room for ellipsis", "日本語", 3, "日"},
+		{"combining mark kept", "ééééé", 4, "é..."},
+		{"emoji not split", "👍🏽👍🏽👍🏽", 5, "👍🏽..."},
+		{"flag not split", "🇯🇵🇯🇵🇯🇵", 5, "🇯🇵..."},
+		{"zwj not split", "👨‍👩‍👧👨‍👩‍👧👨‍👩‍👧", 5, "👨‍👩‍👧..."},
+		{"color reset", "\x1b[31mhello world\x1b[0m", 8, "\x1b[31mhello\x1b[0m..."},
+		{"color fits", "\x1b[31mhello\x1b[0m", 5, "\x1b[31mhello\x1b[0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.maxLen)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.maxLen, got, tt.want)
+			}
+			if w := DisplayWidth(got); w > tt.maxLen {
+				t.Errorf("Truncate(%q, %d) is %d columns wide", tt.s, tt.maxLen, w)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name, text  string
+		width       int
+		first, rest string
+		want        string
+	}{
+		{"fits", "one two", 10, "", "", "one two"},
+		{"wraps", "one two three", 8, "", "", "one two\nthree"},
+		{"prefixes", "one two three", 9, "- ", "  ", "- one two\n  three"},
+		{"wide", "日本 語テ キスト", 6, "", "", "日本\n語テ\nキスト"},
+		{"long word", "a verylongword b", 5, "", "", "a\nverylongword\nb"},
+		{"hard breaks", "one\ntwo", 10, "> ", "> ", "> one\n> two"},
+		{"no width", "one two three", 0, "* ", "", "* one two three"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Wrap(tt.text, tt.width, tt.first, tt.rest); got != tt.want {
+				t.Errorf("Wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package safename checks IDs and filenames given by the server before
+// they are used as file names.
+package safename
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafe is returned by Check for names that are not safe to use.
+var ErrUnsafe = errors.New("unsafe name")
+
+// Check returns an error wrapping ErrUnsafe if name cannot be used as a
+// single path element: if it is empty or ".", contains "..", a slash or a
+// backslash, or is absolute.
+func Check(name string) error {
+	if name == "" || name == "." || strings.Contains(name, "..") ||
+		strings.ContainsAny(name, `/\`) || filepath.Base(name) != name ||
+		filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%w %q", ErrUnsafe, name)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Package xdg locates the per-user directories of manuals, following the
+// XDG Base Directory conventions.
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// name is the directory name of manuals under each base directory.
+const name = "manuals"
+
+// ConfigDir returns the config directory: $XDG_CONFIG_HOME/manuals, or
+// ~/.config/manuals.
+func ConfigDir() (string, error) {
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the data directory: $XDG_DATA_HOME/manuals, or
+// ~/.local/share/manuals.
+func DataDir() (string, error) {
+	return baseDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// CacheDir returns the cache directory: the user cache directory of the
+// platform ($XDG_CACHE_HOME or ~/.cache on Unix) joined with manuals.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// baseDir returns the manuals directory under the base directory named by
+// env, or under home/fallback if env is unset.
+func baseDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, name), nil
+}